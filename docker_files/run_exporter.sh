@@ -6,7 +6,13 @@ set -e
 EXPORTER_COMMAND="/gpbackup_exporter \
 --web.telemetry-path=${EXPORTER_TELEMETRY_PATH} \
 --web.listen-address=:${EXPORTER_PORT} \
---web.config.file=${EXPORTER_CONFIG} \
+--web.config.file=${EXPORTER_CONFIG}"
+
+if [ -n "${CONFIG_FILE}" ]; then
+    # Settings for collecting metrics are read from configuration file.
+    EXPORTER_COMMAND="${EXPORTER_COMMAND} --config.file=${CONFIG_FILE}"
+else
+    EXPORTER_COMMAND="${EXPORTER_COMMAND} \
 --collect.interval=${COLLECT_INTERVAL} \
 --collect.depth=${COLLECT_DEPTH} \
 --gpbackup.history-file=${HISTORY_FILE} \
@@ -14,11 +20,12 @@ EXPORTER_COMMAND="/gpbackup_exporter \
 --gpbackup.db-exclude=${DB_EXCLUDE} \
 --gpbackup.backup-type=${BACKUP_TYPE}"
 
-# Check variable for enabling collecting metrics for deleted backups.
-[ "${COLLECT_DELETED}" == "true" ] &&  EXPORTER_COMMAND="${EXPORTER_COMMAND} --gpbackup.collect-deleted"
+    # Check variable for enabling collecting metrics for deleted backups.
+    [ "${COLLECT_DELETED}" == "true" ] &&  EXPORTER_COMMAND="${EXPORTER_COMMAND} --gpbackup.collect-deleted"
 
-# Check variable for enabling collecting metrics for failed backups.
-[ "${COLLECT_FAILED}" == "true" ] && EXPORTER_COMMAND="${EXPORTER_COMMAND} --gpbackup.collect-failed"
+    # Check variable for enabling collecting metrics for failed backups.
+    [ "${COLLECT_FAILED}" == "true" ] && EXPORTER_COMMAND="${EXPORTER_COMMAND} --gpbackup.collect-failed"
+fi
 
 # Execute the final command.
 exec ${EXPORTER_COMMAND}
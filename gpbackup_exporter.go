@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log/slog"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -15,67 +18,356 @@ import (
 	"github.com/prometheus/common/promslog"
 	"github.com/prometheus/common/promslog/flag"
 	"github.com/prometheus/common/version"
+	"github.com/prometheus/exporter-toolkit/web"
 	"github.com/prometheus/exporter-toolkit/web/kingpinflag"
 	"github.com/woblerr/gpbackup_exporter/gpbckpexporter"
 )
 
 const exporterName = "gpbackup_exporter"
 
-func main() {
-	var (
-		webPath = kingpin.Flag(
+// Flags, which can be specified in configuration file.
+var configFileFlags = []string{
+	"collect.interval",
+	"collect.watch",
+	"collect.watch-debounce",
+	"collect.depth",
+	"gpbackup.history-file",
+	"gpbackup.history-source",
+	"gpbackup.db-include",
+	"gpbackup.db-exclude",
+	"gpbackup.db-expected",
+	"gpbackup.catalog-db",
+	"gpbackup.catalog-timeout",
+	"gpbackup.in-progress-threshold",
+	"gpbackup.backup-type",
+	"gpbackup.collect-deleted",
+	"gpbackup.collect-failed",
+	"gpbackup.busy-timeout",
+	"gpbackup.lock-retries",
+	"gpbackup.lock-retry-backoff",
+	"gpbackup.snapshot-copy",
+	"gpbackup.snapshot-dir",
+	"gpbackup.deletion-state-file",
+}
+
+// Command line flags.
+type exporterFlags struct {
+	configFile                 *string
+	webPath                    *string
+	webAdditionalToolkitFlags  *web.FlagConfig
+	webEnableLifecycle         *bool
+	webReadyIntervalFactor     *int
+	collectionInterval         *int
+	collectionWatch            *bool
+	collectionWatchDebounce    *int
+	collectionDepth            *int
+	gpbckpHistoryFilePath      *string
+	gpbckpHistorySources       *[]gpbckpexporter.HistorySource
+	gpbckpIncludeDB            *[]string
+	gpbckpExcludeDB            *[]string
+	gpbckpExpectedDB           *[]string
+	gpbckpCatalogDB            *string
+	gpbckpCatalogTimeout       *int
+	gpbckpInProgressThreshold  *int
+	gpbckpBackupType           *string
+	gpbckpBackupCollectDeleted *bool
+	gpbckpBackupCollectFailed  *bool
+	gpbckpBusyTimeout          *int
+	gpbckpLockRetries          *int
+	gpbckpLockRetryBackoff     *int
+	gpbckpSnapshotCopy         *bool
+	gpbckpSnapshotDir          *string
+	gpbckpDeletionStateFile    *string
+	promslogConfig             *promslog.Config
+	// Targets for probe endpoint from configuration file.
+	probeTargets []gpbckpexporter.ProbeTarget
+	// Backup SLA from configuration file.
+	sla gpbckpexporter.SLAConfig
+	// Flags specified in command line by name.
+	setByUser map[string]*bool
+}
+
+// Create application with all command line flags.
+// Parsed values can't be reset, so new application is created for each configuration reload.
+func newApplication() (*kingpin.Application, *exporterFlags) {
+	app := kingpin.New(filepath.Base(os.Args[0]), "")
+	flags := &exporterFlags{
+		configFile: app.Flag(
+			"config.file",
+			"Path to configuration file with gpbackup.* and collect.* settings. Flags override values from file.",
+		).Default("").String(),
+		webPath: app.Flag(
 			"web.telemetry-path",
 			"Path under which to expose metrics.",
-		).Default("/metrics").String()
-		webAdditionalToolkitFlags = kingpinflag.AddFlags(kingpin.CommandLine, ":19854")
-		collectionInterval        = kingpin.Flag(
+		).Default("/metrics").String(),
+		webAdditionalToolkitFlags: kingpinflag.AddFlags(app, ":19854"),
+		webEnableLifecycle: app.Flag(
+			"web.enable-lifecycle",
+			"Enable reload via HTTP request.",
+		).Default("false").Bool(),
+		webReadyIntervalFactor: app.Flag(
+			"web.ready-interval-factor",
+			"Readiness endpoint reports not ready, when the last successful collection is older than this number of collection intervals. 0 - disable.",
+		).Default("3").Int(),
+		collectionInterval: app.Flag(
 			"collect.interval",
 			"Collecting metrics interval in seconds.",
-		).Default("600").Int()
-		collectionDepth = kingpin.Flag(
+		).Default("600").Int(),
+		collectionWatch: app.Flag(
+			"collect.watch",
+			"Collecting metrics after changes of history file. Linux only.",
+		).Default("false").Bool(),
+		collectionWatchDebounce: app.Flag(
+			"collect.watch-debounce",
+			"Delay in seconds after the last change of history file before collecting metrics in watch mode.",
+		).Default("5").Int(),
+		collectionDepth: app.Flag(
 			"collect.depth",
-			"Metrics depth collection in days. Metrics for backup older than this interval will not be collected. 0 - disable.",
-		).Default("0").Int()
-		gpbckpHistoryFilePath = kingpin.Flag(
+			"Metrics depth collection in days. Metrics for backup older than this interval will not be collected. Absence of backups, coverage and SLA are evaluated regardless of depth. 0 or negative value - disable.",
+		).Default("0").Int(),
+		gpbckpHistoryFilePath: app.Flag(
 			"gpbackup.history-file",
-			"Path to gpbackup_history.db.",
-		).Default("").String()
-		gpbckpIncludeDB = kingpin.Flag(
+			"Path to gpbackup_history.db or gpbackup_history.yaml.",
+		).Default("").String(),
+		gpbckpHistorySources: historySourcesFlag(app.Flag(
+			"gpbackup.history-source",
+			"Named history file for collecting metrics, name is used as cluster label. Format: <name>=<path>. Can be specified several times.",
+		).PlaceHolder("NAME=PATH")),
+		gpbckpIncludeDB: app.Flag(
 			"gpbackup.db-include",
 			"Specific db for collecting metrics. Can be specified several times.",
-		).Default("").PlaceHolder("\"\"").Strings()
-		gpbckpExcludeDB = kingpin.Flag(
+		).Default("").PlaceHolder("\"\"").Strings(),
+		gpbckpExcludeDB: app.Flag(
 			"gpbackup.db-exclude",
 			"Specific db to exclude from collecting metrics. Can be specified several times.",
-		).Default("").PlaceHolder("\"\"").Strings()
-		gpbckpBackupType = kingpin.Flag(
+		).Default("").PlaceHolder("\"\"").Strings(),
+		gpbckpExpectedDB: app.Flag(
+			"gpbackup.db-expected",
+			"Specific db, which must have backups. Metrics are set even if db has no backups. Can be specified several times.",
+		).Default("").PlaceHolder("\"\"").Strings(),
+		gpbckpCatalogDB: app.Flag(
+			"gpbackup.catalog-db",
+			"Database for connection to Greenplum coordinator to get list of expected databases from catalog. Connection parameters are taken from PGHOST, PGPORT, PGUSER and PGSSLMODE environment variables. Empty - disable.",
+		).Default("").String(),
+		gpbckpCatalogTimeout: app.Flag(
+			"gpbackup.catalog-timeout",
+			"Timeout in seconds for getting list of databases from catalog, including connection to coordinator.",
+		).Default("5").Int(),
+		gpbckpBackupType: app.Flag(
 			"gpbackup.backup-type",
 			"Specific backup type for collecting metrics. One of: [full, incremental, data-only, metadata-only].",
-		).Default("").String()
-		gpbckpBackupCollectDeleted = kingpin.Flag(
+		).Default("").String(),
+		gpbckpInProgressThreshold: app.Flag(
+			"gpbackup.in-progress-threshold",
+			"Backups with status In Progress longer than this interval in seconds are marked as stuck. 0 - disable.",
+		).Default("0").Int(),
+		gpbckpBackupCollectDeleted: app.Flag(
 			"gpbackup.collect-deleted",
 			"Collecting metrics for deleted backups.",
-		).Default("false").Bool()
-		gpbckpBackupCollectFailed = kingpin.Flag(
+		).Default("false").Bool(),
+		gpbckpBackupCollectFailed: app.Flag(
 			"gpbackup.collect-failed",
 			"Collecting metrics for failed backups.",
-		).Default("false").Bool()
-	)
-	// Set logger config.
-	promslogConfig := &promslog.Config{}
+		).Default("false").Bool(),
+		gpbckpBusyTimeout: app.Flag(
+			"gpbackup.busy-timeout",
+			"Time in seconds to wait for lock on history database within one attempt.",
+		).Default("5").Int(),
+		gpbckpLockRetries: app.Flag(
+			"gpbackup.lock-retries",
+			"Number of retries, when history database is locked. 0 - disable.",
+		).Default("3").Int(),
+		gpbckpLockRetryBackoff: app.Flag(
+			"gpbackup.lock-retry-backoff",
+			"Delay in seconds before the first retry, when history database is locked. The delay is doubled for each next retry.",
+		).Default("1").Int(),
+		gpbckpSnapshotCopy: app.Flag(
+			"gpbackup.snapshot-copy",
+			"Read consistent copy of history file instead of original file. For history files on NFS or other shared storages.",
+		).Default("false").Bool(),
+		gpbckpSnapshotDir: app.Flag(
+			"gpbackup.snapshot-dir",
+			"Directory for copies of history file in snapshot copy mode. Default directory for temporary files is used, if empty.",
+		).Default("").String(),
+		gpbckpDeletionStateFile: app.Flag(
+			"gpbackup.deletion-state-file",
+			"File for saving time when backups were first seen in non-terminal deletion states, so deletion age isn't reset after restart. Empty - state isn't saved.",
+		).Default("").String(),
+		// Set logger config.
+		promslogConfig: &promslog.Config{},
+		setByUser:      make(map[string]*bool, len(configFileFlags)),
+	}
+	for _, name := range configFileFlags {
+		setByUser := new(bool)
+		app.GetFlag(name).IsSetByUser(setByUser)
+		flags.setByUser[name] = setByUser
+	}
 	// Add flags log.level and log.format from promlog package.
-	flag.AddFlags(kingpin.CommandLine, promslogConfig)
-	kingpin.Version(version.Print(exporterName))
+	flag.AddFlags(app, flags.promslogConfig)
+	app.Version(version.Print(exporterName))
 	// Add short help flag.
-	kingpin.HelpFlag.Short('h')
+	app.HelpFlag.Short('h')
+	return app, flags
+}
+
+// Set values from configuration file for flags, which aren't specified in command line.
+func (f *exporterFlags) applyConfigFile() error {
+	if *f.configFile == "" {
+		return nil
+	}
+	config, err := gpbckpexporter.LoadConfigFile(*f.configFile)
+	if err != nil {
+		return err
+	}
+	setFlagValue(f.collectionInterval, config.Collect.Interval, *f.setByUser["collect.interval"])
+	setFlagValue(f.collectionWatch, config.Collect.Watch, *f.setByUser["collect.watch"])
+	setFlagValue(f.collectionWatchDebounce, config.Collect.WatchDebounce, *f.setByUser["collect.watch-debounce"])
+	setFlagValue(f.collectionDepth, config.Collect.Depth, *f.setByUser["collect.depth"])
+	setFlagValue(f.gpbckpHistoryFilePath, config.GPBackup.HistoryFile, *f.setByUser["gpbackup.history-file"])
+	setFlagValue(f.gpbckpHistorySources, config.GPBackup.HistorySources, *f.setByUser["gpbackup.history-source"])
+	setFlagValue(f.gpbckpIncludeDB, config.GPBackup.DBInclude, *f.setByUser["gpbackup.db-include"])
+	setFlagValue(f.gpbckpExcludeDB, config.GPBackup.DBExclude, *f.setByUser["gpbackup.db-exclude"])
+	setFlagValue(f.gpbckpExpectedDB, config.GPBackup.DBExpected, *f.setByUser["gpbackup.db-expected"])
+	setFlagValue(f.gpbckpCatalogDB, config.GPBackup.CatalogDB, *f.setByUser["gpbackup.catalog-db"])
+	setFlagValue(f.gpbckpCatalogTimeout, config.GPBackup.CatalogTimeout, *f.setByUser["gpbackup.catalog-timeout"])
+	setFlagValue(f.gpbckpInProgressThreshold, config.GPBackup.InProgressThreshold, *f.setByUser["gpbackup.in-progress-threshold"])
+	setFlagValue(f.gpbckpBackupType, config.GPBackup.BackupType, *f.setByUser["gpbackup.backup-type"])
+	setFlagValue(f.gpbckpBackupCollectDeleted, config.GPBackup.CollectDeleted, *f.setByUser["gpbackup.collect-deleted"])
+	setFlagValue(f.gpbckpBackupCollectFailed, config.GPBackup.CollectFailed, *f.setByUser["gpbackup.collect-failed"])
+	setFlagValue(f.gpbckpBusyTimeout, config.GPBackup.BusyTimeout, *f.setByUser["gpbackup.busy-timeout"])
+	setFlagValue(f.gpbckpLockRetries, config.GPBackup.LockRetries, *f.setByUser["gpbackup.lock-retries"])
+	setFlagValue(f.gpbckpLockRetryBackoff, config.GPBackup.LockRetryBackoff, *f.setByUser["gpbackup.lock-retry-backoff"])
+	setFlagValue(f.gpbckpSnapshotCopy, config.GPBackup.SnapshotCopy, *f.setByUser["gpbackup.snapshot-copy"])
+	setFlagValue(f.gpbckpSnapshotDir, config.GPBackup.SnapshotDir, *f.setByUser["gpbackup.snapshot-dir"])
+	setFlagValue(f.gpbckpDeletionStateFile, config.GPBackup.DeletionStateFile, *f.setByUser["gpbackup.deletion-state-file"])
+	f.probeTargets = config.Probe.ProbeTargets()
+	f.sla = config.SLA
+	return nil
+}
+
+// Set flag value from configuration file.
+// Value from command line has a higher priority.
+func setFlagValue[T any](flagValue, fileValue *T, setByUser bool) {
+	if fileValue != nil && !setByUser {
+		*flagValue = *fileValue
+	}
+}
+
+// Get history sources for collecting metrics.
+// When named sources aren't specified, history file is used as the only source with empty name.
+// When only probe targets are specified, there are no sources for periodic collection.
+func (f *exporterFlags) historySources() ([]gpbckpexporter.HistorySource, error) {
+	if len(*f.gpbckpHistorySources) == 0 {
+		if *f.gpbckpHistoryFilePath == "" && len(f.probeTargets) != 0 {
+			return nil, nil
+		}
+		return []gpbckpexporter.HistorySource{{HistoryFile: *f.gpbckpHistoryFilePath}}, nil
+	}
+	if *f.gpbckpHistoryFilePath != "" {
+		return nil, errors.New("history file and named history sources can't be used together")
+	}
+	if err := gpbckpexporter.ValidateHistorySources(*f.gpbckpHistorySources); err != nil {
+		return nil, err
+	}
+	if err := gpbckpexporter.ValidateCatalogDB(*f.gpbckpCatalogDB, *f.gpbckpHistorySources); err != nil {
+		return nil, err
+	}
+	return *f.gpbckpHistorySources, nil
+}
+
+// Repeatable flag value for named history sources.
+type historySourcesValue []gpbckpexporter.HistorySource
+
+// Add repeatable flag for named history sources.
+func historySourcesFlag(s kingpin.Settings) *[]gpbckpexporter.HistorySource {
+	value := &historySourcesValue{}
+	s.SetValue(value)
+	return (*[]gpbckpexporter.HistorySource)(value)
+}
+
+func (v *historySourcesValue) Set(value string) error {
+	source, err := gpbckpexporter.ParseHistorySource(value)
+	if err != nil {
+		return err
+	}
+	*v = append(*v, source)
+	return nil
+}
+
+func (v *historySourcesValue) String() string {
+	sources := make([]string, 0, len(*v))
+	for _, source := range *v {
+		sources = append(sources, source.Name+"="+source.HistoryFile)
+	}
+	return strings.Join(sources, ",")
+}
+
+func (v *historySourcesValue) IsCumulative() bool {
+	return true
+}
+
+// Get settings for collecting metrics, which can be reloaded.
+func (f *exporterFlags) collectConfig() gpbckpexporter.CollectConfig {
+	return gpbckpexporter.CollectConfig{
+		BackupType:          *f.gpbckpBackupType,
+		CollectDeleted:      *f.gpbckpBackupCollectDeleted,
+		CollectFailed:       *f.gpbckpBackupCollectFailed,
+		DBInclude:           *f.gpbckpIncludeDB,
+		DBExclude:           *f.gpbckpExcludeDB,
+		DBExpected:          *f.gpbckpExpectedDB,
+		CatalogDB:           *f.gpbckpCatalogDB,
+		CatalogTimeout:      *f.gpbckpCatalogTimeout,
+		CollectDepth:        *f.collectionDepth,
+		InProgressThreshold: *f.gpbckpInProgressThreshold,
+		SLA:                 f.sla,
+	}
+}
+
+// Get settings for access to history database.
+func (f *exporterFlags) historyDBOptions() gpbckpexporter.HistoryDBOptions {
+	return gpbckpexporter.HistoryDBOptions{
+		BusyTimeout:      time.Duration(*f.gpbckpBusyTimeout) * time.Second,
+		LockRetries:      *f.gpbckpLockRetries,
+		LockRetryBackoff: time.Duration(*f.gpbckpLockRetryBackoff) * time.Second,
+		SnapshotCopy:     *f.gpbckpSnapshotCopy,
+		SnapshotDir:      *f.gpbckpSnapshotDir,
+	}
+}
+
+// Load settings for collecting metrics from command line arguments and configuration file.
+// Arguments can be read from file via kingpin '@file' syntax,
+// in this case file is read again on each reload.
+func loadCollectConfig() (gpbckpexporter.CollectConfig, error) {
+	app, flags := newApplication()
+	if _, err := app.Parse(os.Args[1:]); err != nil {
+		return gpbckpexporter.CollectConfig{}, err
+	}
+	if err := flags.applyConfigFile(); err != nil {
+		return gpbckpexporter.CollectConfig{}, err
+	}
+	// History sources aren't reloaded, but catalog can't be enabled for several sources.
+	if _, err := flags.historySources(); err != nil {
+		return gpbckpexporter.CollectConfig{}, err
+	}
+	return flags.collectConfig(), nil
+}
+
+func main() {
+	app, flags := newApplication()
 	// Load command line arguments.
-	kingpin.Parse()
+	kingpin.MustParse(app.Parse(os.Args[1:]))
 	// Setup signal catching.
 	sigs := make(chan os.Signal, 1)
 	// Catch  listed signals.
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	// Catch signal for configuration reload.
+	hups := make(chan os.Signal, 1)
+	signal.Notify(hups, syscall.SIGHUP)
 	// Set logger.
-	logger := promslog.New(promslogConfig)
+	logger := promslog.New(flags.promslogConfig)
+	// Context is canceled upon seeing signal.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	// Method invoked upon seeing signal.
 	go func(logger *slog.Logger) {
 		s := <-sigs
@@ -83,69 +375,229 @@ func main() {
 			"Stopping exporter",
 			"name", filepath.Base(os.Args[0]),
 			"signal", s)
-		os.Exit(1)
+		gpbckpexporter.NotifyStopping(logger)
+		cancel()
 	}(logger)
 	logger.Info(
 		"Starting exporter",
 		"name", filepath.Base(os.Args[0]),
 		"version", version.Info())
 	logger.Info("Build context", "build_context", version.BuildContext())
-	logger.Info(
-		"History database file path",
-		"file", *gpbckpHistoryFilePath)
+	if err := flags.applyConfigFile(); err != nil {
+		logger.Error("Load configuration file failed", "err", err)
+		os.Exit(1)
+	}
+	if *flags.configFile != "" {
+		logger.Info(
+			"Configuration file path",
+			"file", *flags.configFile)
+	}
+	reloader, err := gpbckpexporter.NewConfigReloader(loadCollectConfig)
+	if err != nil {
+		logger.Error("Load configuration failed", "err", err)
+		os.Exit(1)
+	}
+	historyDBOptions := flags.historyDBOptions()
+	if err := historyDBOptions.Validate(); err != nil {
+		logger.Error("Invalid history database settings", "err", err)
+		os.Exit(1)
+	}
+	sources, err := flags.historySources()
+	if err != nil {
+		logger.Error("Invalid history sources", "err", err)
+		os.Exit(1)
+	}
+	for _, source := range sources {
+		logger.Info(
+			"History database file path",
+			"cluster", source.Name,
+			"file", source.HistoryFile)
+	}
 	logger.Info(
 		"Collecting metrics for deleted and failed backups",
-		"deleted", *gpbckpBackupCollectDeleted,
-		"failed", gpbckpBackupCollectFailed,
+		"deleted", *flags.gpbckpBackupCollectDeleted,
+		"failed", *flags.gpbckpBackupCollectFailed,
+	)
+	logger.Info(
+		"History database access settings",
+		"busy_timeout", historyDBOptions.BusyTimeout,
+		"lock_retries", historyDBOptions.LockRetries,
+		"lock_retry_backoff", historyDBOptions.LockRetryBackoff,
 	)
-	if *collectionDepth > 0 {
+	if historyDBOptions.SnapshotCopy {
+		logger.Info(
+			"Read copy of history file",
+			"dir", historyDBOptions.SnapshotDir)
+	}
+	if *flags.gpbckpDeletionStateFile != "" {
+		logger.Info(
+			"Deletion state file path",
+			"file", *flags.gpbckpDeletionStateFile)
+	}
+	if *flags.collectionWatch {
+		logger.Info(
+			"Collecting metrics after changes of history file",
+			"debounce", *flags.collectionWatchDebounce)
+	}
+	if *flags.collectionDepth > 0 {
 		logger.Info(
 			"Metrics depth collection in days",
-			"depth", *collectionDepth)
+			"depth", *flags.collectionDepth)
 	}
-	if strings.Join(*gpbckpIncludeDB, "") != "" {
-		for _, db := range *gpbckpIncludeDB {
+	if strings.Join(*flags.gpbckpIncludeDB, "") != "" {
+		for _, db := range *flags.gpbckpIncludeDB {
 			logger.Info(
 				"Collecting metrics for specific DB",
 				"DB", db)
 		}
 	}
-	if strings.Join(*gpbckpExcludeDB, "") != "" {
-		for _, db := range *gpbckpExcludeDB {
+	if strings.Join(*flags.gpbckpExcludeDB, "") != "" {
+		for _, db := range *flags.gpbckpExcludeDB {
 			logger.Info(
 				"Exclude collecting metrics for specific DB",
 				"DB", db)
 		}
 	}
-	if *gpbckpBackupType != "" {
+	if strings.Join(*flags.gpbckpExpectedDB, "") != "" {
+		for _, db := range *flags.gpbckpExpectedDB {
+			logger.Info(
+				"Expecting backups for specific DB",
+				"DB", db)
+		}
+	}
+	if *flags.gpbckpCatalogDB != "" {
+		logger.Info(
+			"Getting expected databases from catalog",
+			"DB", *flags.gpbckpCatalogDB)
+	}
+	if *flags.gpbckpInProgressThreshold > 0 {
+		logger.Info(
+			"Threshold for backups in progress in seconds",
+			"threshold", *flags.gpbckpInProgressThreshold)
+	}
+	if *flags.gpbckpBackupType != "" {
 		logger.Info(
 			"Collecting metrics for specific backup type",
-			"type", *gpbckpBackupType)
+			"type", *flags.gpbckpBackupType)
 	}
+	// Method invoked upon seeing reload signal.
+	go func(logger *slog.Logger) {
+		for {
+			select {
+			case <-hups:
+				if err := reloader.Reload(logger); err != nil {
+					logger.Error("Reload configuration via signal failed", "err", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}(logger)
 	// Setup parameters for exporter.
-	gpbckpexporter.SetPromPortAndPath(*webAdditionalToolkitFlags, *webPath)
+	gpbckpexporter.SetPromPortAndPath(*flags.webAdditionalToolkitFlags, *flags.webPath)
+	gpbckpexporter.SetHistoryDBOptions(historyDBOptions)
+	if err := gpbckpexporter.SetDeletionStateFile(*flags.gpbckpDeletionStateFile); err != nil {
+		logger.Warn("Load deletion state file failed, deletion ages are reset", "file", *flags.gpbckpDeletionStateFile, "err", err)
+	}
+	if *flags.webEnableLifecycle {
+		gpbckpexporter.SetReloadEndpoint(reloader)
+	}
+	clusters := make([]string, 0, len(sources))
+	for _, source := range sources {
+		clusters = append(clusters, source.Name)
+	}
+	// Readiness depends only on periodic collection.
+	readyMaxAge := time.Duration(*flags.webReadyIntervalFactor) * time.Duration(*flags.collectionInterval) * time.Second
+	gpbckpexporter.SetHealthSources(clusters, readyMaxAge)
+	if len(flags.probeTargets) != 0 {
+		gpbckpexporter.SetProbeTargets(flags.probeTargets)
+		for _, target := range flags.probeTargets {
+			logger.Info(
+				"Probe target",
+				"target", target.Name,
+				"file", target.HistoryFile)
+		}
+	}
 	logger.Info(
 		"Use exporter parameters",
-		"endpoint", *webPath,
-		"config.file", *webAdditionalToolkitFlags.WebConfigFile,
+		"endpoint", *flags.webPath,
+		"config.file", *flags.webAdditionalToolkitFlags.WebConfigFile,
+		"lifecycle", *flags.webEnableLifecycle,
+		"ready_interval_factor", *flags.webReadyIntervalFactor,
 	)
+	watchdogInterval, err := gpbckpexporter.SystemdWatchdogInterval()
+	if err != nil {
+		logger.Warn("Get systemd watchdog settings failed", "err", err)
+	}
+	// Watchdog is updated after collections of all history sources.
+	if watchdogInterval > 0 && watchdogInterval <= time.Duration(*flags.collectionInterval)*time.Second {
+		logger.Warn(
+			"Systemd watchdog timeout is less than collecting metrics interval, exporter will be restarted",
+			"watchdog", watchdogInterval,
+			"interval", *flags.collectionInterval)
+	}
 	// Exporter build info metric.
 	prometheus.MustRegister(version_collector.NewCollector(exporterName))
+	// Configuration reload metrics.
+	prometheus.MustRegister(gpbckpexporter.GetConfigReloadMetrics()...)
+	// Collection metrics.
+	prometheus.MustRegister(gpbckpexporter.GetCollectionMetrics()...)
+	// Backup metrics collector.
+	prometheus.MustRegister(gpbckpexporter.GetCollector())
 	// Start web server.
-	gpbckpexporter.StartPromEndpoint(version.Info(), logger)
-	for {
+	serverDone := gpbckpexporter.StartPromEndpoint(ctx, version.Info(), logger)
+	// Channels for history files changes, they are nil when watch mode is disabled.
+	watchTriggers := make([]<-chan struct{}, len(sources))
+	if *flags.collectionWatch {
+		for i, source := range sources {
+			watchTriggers[i], err = gpbckpexporter.WatchHistoryFile(
+				source.HistoryFile,
+				time.Duration(*flags.collectionWatchDebounce)*time.Second,
+				ctx.Done(),
+				logger,
+			)
+			if err != nil {
+				logger.Error("Watch history file failed", "file", source.HistoryFile, "err", err)
+				os.Exit(1)
+			}
+		}
+	}
+	// Each history source is collected independently.
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			collectMetrics(
+				ctx,
+				source,
+				reloader,
+				time.Duration(*flags.collectionInterval)*time.Second,
+				watchTriggers[i],
+				logger,
+			)
+		}()
+	}
+	// Notify systemd, when exporter is ready to serve metrics.
+	go gpbckpexporter.NotifyReady(ctx, logger)
+	wg.Wait()
+	// Wait for web server shutdown.
+	<-serverDone
+	logger.Info("Exporter stopped")
+}
+
+// Collect metrics for history source until context is canceled.
+func collectMetrics(ctx context.Context, source gpbckpexporter.HistorySource, reloader *gpbckpexporter.ConfigReloader, interval time.Duration, watchTrigger <-chan struct{}, logger *slog.Logger) {
+	for ctx.Err() == nil {
+		// Settings can be changed by reload between collections.
+		collectConfig := reloader.Config()
 		// Get information form gpbackup_history.db.
-		gpbckpexporter.GetGPBackupInfo(
-			*gpbckpHistoryFilePath,
-			*gpbckpBackupType,
-			*gpbckpBackupCollectDeleted,
-			*gpbckpBackupCollectFailed,
-			*gpbckpIncludeDB,
-			*gpbckpExcludeDB,
-			*collectionDepth,
-			logger,
-		)
-		// Sleep for 'collection.interval' seconds.
-		time.Sleep(time.Duration(*collectionInterval) * time.Second)
+		gpbckpexporter.GetGPBackupInfo(ctx, source.Name, source.HistoryFile, collectConfig, logger)
+		// Sleep for 'collection.interval' seconds or until history file is changed in watch mode.
+		select {
+		case <-time.After(interval):
+		case <-watchTrigger:
+		case <-ctx.Done():
+		}
 	}
 }
@@ -7,9 +7,14 @@ import (
 	"math/big"
 	"net/http"
 	"os"
+	"reflect"
 	"strconv"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
+
+	"github.com/woblerr/gpbackup_exporter/gpbckpexporter"
 )
 
 func TestMain(t *testing.T) {
@@ -53,4 +58,110 @@ func TestMain(t *testing.T) {
 			}
 		})
 	}
+	// Exporter must stop gracefully after signal.
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatalf("\nGet error during send signal:\n%v", err)
+	}
+	select {
+	case <-finished:
+	case <-time.After(15 * time.Second):
+		t.Fatalf("\nExporter was not stopped after signal")
+	}
+}
+
+func TestApplyConfigFile(t *testing.T) {
+	configFile, err := os.CreateTemp("", "gpbackup_exporter*.yml")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(configFile.Name())
+	data := `gpbackup:
+  history_file: /tmp/gpbackup_history.db
+  backup_type: full
+  db_exclude: [test]
+collect:
+  interval: 60
+`
+	if _, err := configFile.WriteString(data); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	configFile.Close()
+	app, flags := newApplication()
+	// Flag from command line overrides value from configuration file.
+	if _, err := app.Parse([]string{"--config.file=" + configFile.Name(), "--gpbackup.backup-type=incremental"}); err != nil {
+		t.Fatalf("\nGet error during parse flags:\n%v", err)
+	}
+	if err := flags.applyConfigFile(); err != nil {
+		t.Fatalf("\nGet error during apply config file:\n%v", err)
+	}
+	if *flags.gpbckpHistoryFilePath != "/tmp/gpbackup_history.db" {
+		t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", *flags.gpbckpHistoryFilePath, "/tmp/gpbackup_history.db")
+	}
+	if *flags.gpbckpBackupType != "incremental" {
+		t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", *flags.gpbckpBackupType, "incremental")
+	}
+	if strings.Join(*flags.gpbckpExcludeDB, ",") != "test" {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", *flags.gpbckpExcludeDB, []string{"test"})
+	}
+	if *flags.collectionInterval != 60 {
+		t.Errorf("\nVariables do not match:\n%d\nwant:\n%d", *flags.collectionInterval, 60)
+	}
+	if *flags.collectionDepth != 0 {
+		t.Errorf("\nVariables do not match:\n%d\nwant:\n%d", *flags.collectionDepth, 0)
+	}
+}
+
+func TestHistorySources(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    []gpbckpexporter.HistorySource
+		wantErr bool
+	}{
+		{
+			"HistoryFile",
+			[]string{"--gpbackup.history-file=/data/gpbackup_history.db"},
+			[]gpbckpexporter.HistorySource{{Name: "", HistoryFile: "/data/gpbackup_history.db"}},
+			false,
+		},
+		{
+			"NamedSources",
+			[]string{"--gpbackup.history-source=cluster1=/data/1.db", "--gpbackup.history-source=cluster2=/data/2.db"},
+			[]gpbckpexporter.HistorySource{{Name: "cluster1", HistoryFile: "/data/1.db"}, {Name: "cluster2", HistoryFile: "/data/2.db"}},
+			false,
+		},
+		{
+			"HistoryFileAndNamedSources",
+			[]string{"--gpbackup.history-file=/data/gpbackup_history.db", "--gpbackup.history-source=cluster1=/data/1.db"},
+			nil,
+			true,
+		},
+		{
+			"CatalogWithNamedSources",
+			[]string{"--gpbackup.catalog-db=postgres", "--gpbackup.history-source=cluster1=/data/1.db", "--gpbackup.history-source=cluster2=/data/2.db"},
+			nil,
+			true,
+		},
+		{
+			"DuplicateNamedSources",
+			[]string{"--gpbackup.history-source=cluster1=/data/1.db", "--gpbackup.history-source=cluster1=/data/2.db"},
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, flags := newApplication()
+			if _, err := app.Parse(tt.args); err != nil {
+				t.Fatalf("\nGet error during parse flags:\n%v", err)
+			}
+			got, err := flags.historySources()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("\nVariables do not match:\n%v\nwantErr:\n%v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
 }
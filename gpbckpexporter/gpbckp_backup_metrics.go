@@ -5,59 +5,14 @@ import (
 	"strconv"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/woblerr/gpbackman/gpbckpconfig"
 )
 
 var (
-	gpbckpBackupStatusMetric = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "gpbackup_backup_status",
-		Help: "Backup status.",
-	},
-		[]string{
-			"backup_type",
-			"database_name",
-			"object_filtering",
-			"plugin",
-			"timestamp"})
-	gpbckpBackupDataDeletedStatusMetric = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "gpbackup_backup_deletion_status",
-		Help: "Backup deletion status.",
-	},
-		[]string{
-			"backup_type",
-			"database_name",
-			"date_deleted",
-			"object_filtering",
-			"plugin",
-			"timestamp"})
-	gpbckpBackupInfoMetric = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "gpbackup_backup_info",
-		Help: "Backup info.",
-	},
-		[]string{
-			"backup_dir",
-			"backup_ver",
-			"backup_type",
-			"compression_type",
-			"database_name",
-			"database_ver",
-			"object_filtering",
-			"plugin",
-			"plugin_ver",
-			"timestamp",
-			"with_statistic"})
-	gpbckpBackupDurationMetric = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "gpbackup_backup_duration_seconds",
-		Help: "Backup duration.",
-	},
-		[]string{
-			"backup_type",
-			"database_name",
-			"end_time",
-			"object_filtering",
-			"plugin",
-			"timestamp"})
+	gpbckpBackupStatusMetric            = newBackupStatusMetric()
+	gpbckpBackupDataDeletedStatusMetric = newBackupDataDeletedStatusMetric()
+	gpbckpBackupInfoMetric              = newBackupInfoMetric()
+	gpbckpBackupDurationMetric          = newBackupDurationMetric()
 )
 
 // Set backup metrics:
@@ -65,7 +20,7 @@ var (
 //   - gpbackup_backup_deletion_status
 //   - gpbackup_backup_info
 //   - gpbackup_backup_duration_seconds
-func getBackupMetrics(backupData gpbckpconfig.BackupConfig, setUpMetricValueFun setUpMetricValueFunType, logger *slog.Logger) {
+func getBackupMetrics(cluster string, backupData gpbckpconfig.BackupConfig, setUpMetricValueFun setUpMetricValueFunType, logger *slog.Logger) {
 	var (
 		bckpDuration float64
 		err          error
@@ -78,12 +33,16 @@ func getBackupMetrics(backupData gpbckpconfig.BackupConfig, setUpMetricValueFun
 	if err != nil {
 		logger.Error("Parse object filtering value failed", "err", err)
 	}
-	bckpDuration, err = backupData.GetBackupDuration()
-	if err != nil {
-		logger.Error(
-			"Failed to parse dates to calculate duration",
-			"err", err,
-		)
+	// End time isn't set, while backup is in progress.
+	// Elapsed time for such backups is set via gpbackup_backup_in_progress_elapsed_seconds.
+	if !backupData.IsInProgress() {
+		bckpDuration, err = backupData.GetBackupDuration()
+		if err != nil {
+			logger.Error(
+				"Failed to parse dates to calculate duration",
+				"err", err,
+			)
+		}
 	}
 	bckpDateDeleted, bckpDeletedStatus := getDeletedStatusCode(backupData.DateDeleted)
 	// Backup status.
@@ -94,6 +53,7 @@ func getBackupMetrics(backupData gpbckpconfig.BackupConfig, setUpMetricValueFun
 		setUpMetricValueFun,
 		logger,
 		bckpType,
+		cluster,
 		backupData.DatabaseName,
 		convertEmptyLabel(backpObjectFiltering),
 		convertEmptyLabel(backupData.Plugin),
@@ -107,6 +67,7 @@ func getBackupMetrics(backupData gpbckpconfig.BackupConfig, setUpMetricValueFun
 		setUpMetricValueFun,
 		logger,
 		bckpType,
+		cluster,
 		backupData.DatabaseName,
 		bckpDateDeleted,
 		convertEmptyLabel(backpObjectFiltering),
@@ -123,6 +84,7 @@ func getBackupMetrics(backupData gpbckpconfig.BackupConfig, setUpMetricValueFun
 		convertEmptyLabel(backupData.BackupDir),
 		backupData.BackupVersion,
 		bckpType,
+		cluster,
 		convertEmptyLabel(backupData.CompressionType),
 		backupData.DatabaseName,
 		backupData.DatabaseVersion,
@@ -132,6 +94,9 @@ func getBackupMetrics(backupData gpbckpconfig.BackupConfig, setUpMetricValueFun
 		backupData.Timestamp,
 		strconv.FormatBool(backupData.WithStatistics),
 	)
+	if backupData.IsInProgress() {
+		return
+	}
 	// Backup duration.
 	setUpMetric(
 		gpbckpBackupDurationMetric,
@@ -140,8 +105,9 @@ func getBackupMetrics(backupData gpbckpconfig.BackupConfig, setUpMetricValueFun
 		setUpMetricValueFun,
 		logger,
 		bckpType,
+		cluster,
 		backupData.DatabaseName,
-		// End time may be not set, if backup in progress.
+		// End time may be not set.
 		convertEmptyLabel(backupData.EndTime),
 		convertEmptyLabel(backpObjectFiltering),
 		convertEmptyLabel(backupData.Plugin),
@@ -155,3 +121,67 @@ func resetBackupMetrics() {
 	gpbckpBackupInfoMetric.Reset()
 	gpbckpBackupDurationMetric.Reset()
 }
+
+func newBackupStatusMetric() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_backup_status",
+		Help: "Backup status.",
+	},
+		[]string{
+			"backup_type",
+			"cluster",
+			"database_name",
+			"object_filtering",
+			"plugin",
+			"timestamp"})
+}
+
+func newBackupDataDeletedStatusMetric() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_backup_deletion_status",
+		Help: "Backup deletion status.",
+	},
+		[]string{
+			"backup_type",
+			"cluster",
+			"database_name",
+			"date_deleted",
+			"object_filtering",
+			"plugin",
+			"timestamp"})
+}
+
+func newBackupInfoMetric() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_backup_info",
+		Help: "Backup info.",
+	},
+		[]string{
+			"backup_dir",
+			"backup_ver",
+			"backup_type",
+			"cluster",
+			"compression_type",
+			"database_name",
+			"database_ver",
+			"object_filtering",
+			"plugin",
+			"plugin_ver",
+			"timestamp",
+			"with_statistic"})
+}
+
+func newBackupDurationMetric() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_backup_duration_seconds",
+		Help: "Backup duration.",
+	},
+		[]string{
+			"backup_type",
+			"cluster",
+			"database_name",
+			"end_time",
+			"object_filtering",
+			"plugin",
+			"timestamp"})
+}
@@ -22,16 +22,16 @@ func TestGetBackupMetrics(t *testing.T) {
 	}
 	templateMetrics := `# HELP gpbackup_backup_deletion_status Backup deletion status.
 # TYPE gpbackup_backup_deletion_status gauge
-gpbackup_backup_deletion_status{backup_type="full",database_name="test",date_deleted="none",object_filtering="none",plugin="none",timestamp="20230118152654"} 0
+gpbackup_backup_deletion_status{backup_type="full",cluster="",database_name="test",date_deleted="none",object_filtering="none",plugin="none",timestamp="20230118152654"} 0
 # HELP gpbackup_backup_duration_seconds Backup duration.
 # TYPE gpbackup_backup_duration_seconds gauge
-gpbackup_backup_duration_seconds{backup_type="full",database_name="test",end_time="20230118152656",object_filtering="none",plugin="none",timestamp="20230118152654"} 2
+gpbackup_backup_duration_seconds{backup_type="full",cluster="",database_name="test",end_time="20230118152656",object_filtering="none",plugin="none",timestamp="20230118152654"} 2
 # HELP gpbackup_backup_info Backup info.
 # TYPE gpbackup_backup_info gauge
-gpbackup_backup_info{backup_dir="/data/backups",backup_type="full",backup_ver="1.30.5",compression_type="gzip",database_name="test",database_ver="6.23.0",object_filtering="none",plugin="none",plugin_ver="none",timestamp="20230118152654",with_statistic="false"} 1
+gpbackup_backup_info{backup_dir="/data/backups",backup_type="full",backup_ver="1.30.5",cluster="",compression_type="gzip",database_name="test",database_ver="6.23.0",object_filtering="none",plugin="none",plugin_ver="none",timestamp="20230118152654",with_statistic="false"} 1
 # HELP gpbackup_backup_status Backup status.
 # TYPE gpbackup_backup_status gauge
-gpbackup_backup_status{backup_type="full",database_name="test",object_filtering="none",plugin="none",timestamp="20230118152654"} 0
+gpbackup_backup_status{backup_type="full",cluster="",database_name="test",object_filtering="none",plugin="none",timestamp="20230118152654"} 0
 `
 	tests := []struct {
 		name string
@@ -47,7 +47,7 @@ gpbackup_backup_status{backup_type="full",database_name="test",object_filtering=
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			resetBackupMetrics()
-			getBackupMetrics(tt.args.backupData, tt.args.setUpMetricValueFun, getLogger())
+			getBackupMetrics("", tt.args.backupData, tt.args.setUpMetricValueFun, getLogger())
 			reg := prometheus.NewRegistry()
 			reg.MustRegister(
 				gpbckpBackupStatusMetric,
@@ -99,6 +99,18 @@ func TestGetBackupMetricsErrorsAndDebugs(t *testing.T) {
 				4,
 			},
 		},
+		{"GetBackupMetricsErrorInProgress",
+			args{
+				// Duration isn't calculated and set for backup in progress.
+				gpbckpconfig.BackupConfig{
+					Timestamp: "20230118152654",
+					Status:    gpbckpconfig.BackupStatusInProgress,
+				},
+				fakeSetUpMetricValue,
+				3,
+				3,
+			},
+		},
 		{"GetBackupMetricsErrorGetBackupTypeAndObjectFilteringError",
 			args{
 				// Fake example for testing.
@@ -119,7 +131,7 @@ func TestGetBackupMetricsErrorsAndDebugs(t *testing.T) {
 			resetBackupMetrics()
 			out := &bytes.Buffer{}
 			lc := slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug}))
-			getBackupMetrics(tt.args.backupData, tt.args.setUpMetricValueFun, lc)
+			getBackupMetrics("", tt.args.backupData, tt.args.setUpMetricValueFun, lc)
 			errorsOutputCount := strings.Count(out.String(), "level=ERROR")
 			debugsOutputCount := strings.Count(out.String(), "level=DEBUG")
 			if tt.args.errorsCount != errorsOutputCount || tt.args.debugsCount != debugsOutputCount {
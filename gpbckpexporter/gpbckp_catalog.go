@@ -0,0 +1,183 @@
+package gpbckpexporter
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	// Driver for connection to Greenplum coordinator.
+	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Databases from Greenplum catalog are used as expected databases,
+// so databases, which have never been backed up, are reported too.
+
+// Values of gpbackup_database_backup_coverage metric.
+const (
+	// There are no successful backups of required type.
+	coverageNone float64 = iota
+	// The last successful backup of required type is older than SLA allows.
+	// Without SLA for required backup type, this state isn't possible.
+	coverageStale
+	// The last successful backup of required type meets SLA.
+	coverageFull
+)
+
+// Query for databases, which can be backed up.
+// Template databases and databases, which don't allow connections, are skipped.
+const catalogDatabasesQuery = `SELECT datname FROM pg_database WHERE datallowconn AND NOT datistemplate ORDER BY datname`
+
+var gpbckpDatabaseBackupCoverageMetric = newDatabaseBackupCoverageMetric()
+
+// Default timeout for getting databases from catalog.
+const defaultCatalogTimeout = 5 * time.Second
+
+// Get names of databases from Greenplum catalog on coordinator.
+// Connection parameters are taken from PGHOST, PGPORT and PGUSER environment variables,
+// the same as for gpbackman, and from PGSSLMODE, if it's set.
+// Connection and query are limited by timeout, so unavailable coordinator doesn't block collection.
+var getCatalogDatabases = func(ctx context.Context, catalogDB string, timeout time.Duration) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	conn, err := sqlx.ConnectContext(ctx, "postgres", catalogConnString(catalogDB, timeout))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var dbNames []string
+	if err := conn.SelectContext(ctx, &dbNames, catalogDatabasesQuery); err != nil {
+		return nil, err
+	}
+	return dbNames, nil
+}
+
+// Get connection string for database on coordinator.
+// Parameters are the same as for gpbckpconfig.NewClusterLocalClusterConn, except connection timeout
+// and SSL mode. If PGSSLMODE environment variable is set, SSL mode isn't specified in connection string,
+// so driver takes it and other PGSSL* parameters from environment variables.
+// Otherwise, SSL is disabled as in gpbackman.
+func catalogConnString(catalogDB string, timeout time.Duration) string {
+	username := os.Getenv("PGUSER")
+	if username == "" {
+		if currentUser, err := user.Current(); err == nil {
+			username = currentUser.Username
+		}
+	}
+	host := os.Getenv("PGHOST")
+	if host == "" {
+		host, _ = os.Hostname()
+	}
+	port, err := strconv.Atoi(os.Getenv("PGPORT"))
+	if err != nil {
+		port = 5432
+	}
+	// Driver limits connection establishment only by connection timeout in seconds,
+	// 0 means infinite wait.
+	query := url.Values{}
+	if os.Getenv("PGSSLMODE") == "" {
+		query.Set("sslmode", "disable")
+	}
+	query.Set("connect_timeout", strconv.Itoa(max(int(timeout.Seconds()), 1)))
+	connURL := url.URL{
+		Scheme:   "postgres",
+		User:     url.User(username),
+		Host:     net.JoinHostPort(host, strconv.Itoa(port)),
+		Path:     catalogDB,
+		RawQuery: query.Encode(),
+	}
+	return connURL.String()
+}
+
+// Get databases from catalog for collection settings.
+// Nil is returned, if catalog isn't set or can't be read.
+func loadCatalogDatabases(ctx context.Context, config CollectConfig, logger *slog.Logger) []string {
+	if config.CatalogDB == "" {
+		return nil
+	}
+	timeout := defaultCatalogTimeout
+	if config.CatalogTimeout > 0 {
+		timeout = time.Duration(config.CatalogTimeout) * time.Second
+	}
+	catalogDBs, err := getCatalogDatabases(ctx, config.CatalogDB, timeout)
+	if err != nil {
+		logger.Error("Get databases from catalog failed", "err", err)
+		return nil
+	}
+	return catalogDBs
+}
+
+// Merge lists of databases without duplicates and empty values.
+func mergeDatabases(lists ...[]string) []string {
+	dbNames := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, list := range lists {
+		for _, db := range list {
+			if _, ok := seen[db]; ok || db == "" {
+				continue
+			}
+			seen[db] = struct{}{}
+			dbNames = append(dbNames, db)
+		}
+	}
+	return dbNames
+}
+
+// Get backup coverage of database by the last successful backup of required type.
+// Freshness of backup is checked only by SLA. Without SLA for required backup type,
+// any successful backup covers database, however old it is.
+func getDatabaseCoverage(db, bckpType string, sla SLAConfig, lastBackups lastBackupMap, currentTime time.Time) float64 {
+	endTime, ok := lastBackups[db][bckpType]
+	if !ok {
+		return coverageNone
+	}
+	if interval, ok := sla.policy(db)[bckpType]; ok && currentTime.Sub(endTime) > time.Duration(interval) {
+		return coverageStale
+	}
+	return coverageFull
+}
+
+// Set database coverage metrics:
+//   - gpbackup_database_backup_coverage
+//
+// Metric is set for databases from catalog, which are selected by databases filters.
+// Stale coverage is reported only for databases with SLA for required backup type.
+func getDatabaseCoverageMetrics(cluster, bckpType string, sla SLAConfig, catalogDBs, dbInclude, dbExclude []string, lastBackups lastBackupMap, currentUnixTime int64, setUpMetricValueFun setUpMetricValueFunType, logger *slog.Logger) {
+	currentTime := time.Unix(currentUnixTime, 0)
+	for _, db := range catalogDBs {
+		if !dbSelected(db, dbInclude, dbExclude) {
+			continue
+		}
+		setUpMetric(
+			gpbckpDatabaseBackupCoverageMetric,
+			"gpbackup_database_backup_coverage",
+			getDatabaseCoverage(db, bckpType, sla, lastBackups, currentTime),
+			setUpMetricValueFun,
+			logger,
+			bckpType,
+			cluster,
+			db,
+		)
+	}
+}
+
+func resetCatalogMetrics() {
+	gpbckpDatabaseBackupCoverageMetric.Reset()
+}
+
+func newDatabaseBackupCoverageMetric() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_database_backup_coverage",
+		Help: "Backup coverage of database from catalog.",
+	},
+		[]string{
+			"backup_type",
+			"cluster",
+			"database_name"})
+}
@@ -0,0 +1,201 @@
+package gpbckpexporter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+)
+
+func TestMergeDatabases(t *testing.T) {
+	got := mergeDatabases([]string{"", "test1", "test2"}, []string{"test2", "test3"})
+	want := []string{"test1", "test2", "test3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestGetDatabaseCoverageMetrics(t *testing.T) {
+	sla := SLAConfig{
+		Databases: map[string]SLAPolicy{
+			"test2": {"full": model.Duration(time.Hour)},
+		},
+	}
+	lastBackups := lastBackupMap{
+		"test1": backupMap{"full": returnTimeTime("20230118150000")},
+		"test2": backupMap{"full": returnTimeTime("20230118150000")},
+		"test4": backupMap{"incremental": returnTimeTime("20230118150000")},
+	}
+	templateMetrics := `# HELP gpbackup_database_backup_coverage Backup coverage of database from catalog.
+# TYPE gpbackup_database_backup_coverage gauge
+gpbackup_database_backup_coverage{backup_type="full",cluster="",database_name="test1"} 2
+gpbackup_database_backup_coverage{backup_type="full",cluster="",database_name="test2"} 1
+gpbackup_database_backup_coverage{backup_type="full",cluster="",database_name="test3"} 0
+gpbackup_database_backup_coverage{backup_type="full",cluster="",database_name="test4"} 0
+`
+	resetCatalogMetrics()
+	getDatabaseCoverageMetrics("", "full", sla, []string{"test1", "test2", "test3", "test4", "test5"}, []string{""}, []string{"test5"}, lastBackups, templateUnixTime(), setUpMetricValue, getLogger())
+	if got := gatherMetricsText(t, gpbckpDatabaseBackupCoverageMetric); got != templateMetrics {
+		t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", got, templateMetrics)
+	}
+}
+
+func TestCollectBackupInfoCatalog(t *testing.T) {
+	historyFile := fakeHistoryFileBackups(t, templateBackupConfig())
+	defer os.Remove(historyFile)
+	tests := []struct {
+		name        string
+		catalogDBs  []string
+		catalogErr  error
+		testText    string
+		errorsCount int
+	}{
+		{
+			"CatalogDatabases",
+			[]string{"postgres", "test"},
+			nil,
+			`# HELP gpbackup_database_backup_coverage Backup coverage of database from catalog.
+# TYPE gpbackup_database_backup_coverage gauge
+gpbackup_database_backup_coverage{backup_type="full",cluster="",database_name="postgres"} 0
+gpbackup_database_backup_coverage{backup_type="full",cluster="",database_name="test"} 2
+# HELP gpbackup_database_backup_missing Whether expected database has no successful backups of required type.
+# TYPE gpbackup_database_backup_missing gauge
+gpbackup_database_backup_missing{backup_type="full",cluster="",database_name="postgres"} 1
+gpbackup_database_backup_missing{backup_type="full",cluster="",database_name="test"} 0
+`,
+			0,
+		},
+		{
+			"CatalogError",
+			nil,
+			errors.New("connection refused"),
+			"",
+			1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(f func(context.Context, string, time.Duration) ([]string, error)) { getCatalogDatabases = f }(getCatalogDatabases)
+			getCatalogDatabases = func(ctx context.Context, catalogDB string, timeout time.Duration) ([]string, error) {
+				if catalogDB != "postgres" {
+					t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", catalogDB, "postgres")
+				}
+				if timeout != 2*time.Second {
+					t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", timeout, 2*time.Second)
+				}
+				return tt.catalogDBs, tt.catalogErr
+			}
+			resetMetrics()
+			out := &bytes.Buffer{}
+			lc := slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{Level: slog.LevelError}))
+			_, err := collectBackupInfo(context.Background(), "", historyFile, CollectConfig{CatalogDB: "postgres", CatalogTimeout: 2}, nil, setUpMetricValue, lc)
+			if err != nil {
+				t.Fatalf("\nGet error during collect backup info:\n%v", err)
+			}
+			if got := gatherMetricsText(t, gpbckpDatabaseBackupCoverageMetric, gpbckpDatabaseBackupMissingMetric); got != tt.testText {
+				t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", got, tt.testText)
+			}
+			if errorsCount := strings.Count(out.String(), "level=ERROR"); errorsCount != tt.errorsCount {
+				t.Errorf("\nVariables do not match:\nerrors=%d\nwant:\nerrors=%d", errorsCount, tt.errorsCount)
+			}
+		})
+	}
+}
+
+func TestCatalogConnString(t *testing.T) {
+	t.Setenv("PGUSER", "gpadmin")
+	t.Setenv("PGHOST", "coordinator")
+	t.Setenv("PGPORT", "6432")
+	tests := []struct {
+		name    string
+		sslMode string
+		want    string
+	}{
+		{"SSLDisabled", "", "postgres://gpadmin@coordinator:6432/postgres?connect_timeout=3&sslmode=disable"},
+		{"SSLModeFromEnv", "verify-full", "postgres://gpadmin@coordinator:6432/postgres?connect_timeout=3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PGSSLMODE", tt.sslMode)
+			if got := catalogConnString("postgres", 3*time.Second); got != tt.want {
+				t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetCatalogDatabasesTimeout(t *testing.T) {
+	// Server accepts connections, but never responds.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	t.Setenv("PGHOST", host)
+	t.Setenv("PGPORT", port)
+	start := time.Now()
+	if _, err := getCatalogDatabases(context.Background(), "postgres", time.Second); err == nil {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\nerror", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n<= %v", elapsed, 2*time.Second)
+	}
+}
+
+// Test against PostgreSQL instance instead of Greenplum coordinator.
+// Connection parameters are taken from PGHOST, PGPORT and PGUSER environment variables.
+// For example, see test-catalog target in Makefile.
+func TestGetCatalogDatabasesPostgres(t *testing.T) {
+	catalogDB := os.Getenv("GPBCKP_TEST_CATALOG_DB")
+	if catalogDB == "" {
+		t.Skip("GPBCKP_TEST_CATALOG_DB isn't set")
+	}
+	dbNames, err := getCatalogDatabases(context.Background(), catalogDB, defaultCatalogTimeout)
+	if err != nil {
+		t.Fatalf("\nGet error during get databases from catalog:\n%v", err)
+	}
+	if !slices.Contains(dbNames, catalogDB) {
+		t.Errorf("\nVariables do not match:\n%v\nwant to contain:\n%s", dbNames, catalogDB)
+	}
+	if slices.Contains(dbNames, "template0") || slices.Contains(dbNames, "template1") {
+		t.Errorf("\nVariables do not match:\n%v\nwant without template databases", dbNames)
+	}
+}
+
+func gatherMetricsText(tb testing.TB, metrics ...prometheus.Collector) string {
+	tb.Helper()
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(metrics...)
+	metricFamily, err := reg.Gather()
+	if err != nil {
+		tb.Fatalf("\nGet error during gather metrics:\n%v", err)
+	}
+	out := &bytes.Buffer{}
+	for _, mf := range metricFamily {
+		if _, err := expfmt.MetricFamilyToText(out, mf); err != nil {
+			tb.Fatalf("\nGet error during convert metrics:\n%v", err)
+		}
+	}
+	return out.String()
+}
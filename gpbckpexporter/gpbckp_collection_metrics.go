@@ -0,0 +1,112 @@
+package gpbckpexporter
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics for collections from history files.
+// Unlike backup metrics, they aren't reset before each collection
+// and are updated only by periodic collection, not by probes.
+var (
+	gpbckpCollectionDurationMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_exporter_collection_duration_seconds",
+		Help: "Duration of the last collection in seconds.",
+	},
+		[]string{"cluster"})
+	gpbckpCollectionLastSuccessTimeMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_exporter_collection_last_success_timestamp_seconds",
+		Help: "Timestamp of the last successful collection.",
+	},
+		[]string{"cluster"})
+	gpbckpCollectionsMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gpbackup_exporter_collections_total",
+		Help: "Total number of collections.",
+	},
+		[]string{"cluster"})
+	gpbckpErrorsMetric = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gpbackup_exporter_errors_total",
+		Help: "Total number of errors during getting data from history database by reason.",
+	},
+		[]string{
+			"cluster",
+			"reason"})
+	gpbckpUpMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_exporter_up",
+		Help: "Whether the last collection from history database was successful.",
+	},
+		[]string{"cluster"})
+	gpbckpSnapshotStaleMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_exporter_snapshot_stale",
+		Help: "Whether metrics from the previous collection are served, because history database is busy.",
+	},
+		[]string{"cluster"})
+	gpbckpCollectionRowsReadMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_exporter_collection_rows_read",
+		Help: "Number of rows read from history file during the last successful collection.",
+	},
+		[]string{"cluster"})
+	gpbckpCollectionRowsFilteredMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_exporter_collection_rows_filtered",
+		Help: "Number of backups filtered out by filter during the last successful collection.",
+	},
+		[]string{
+			"cluster",
+			"filter"})
+	gpbckpCollectionSeriesMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_exporter_collection_series",
+		Help: "Number of metric series served after the last collection.",
+	},
+		[]string{"cluster"})
+)
+
+// GetCollectionMetrics returns metrics for collections from history files.
+func GetCollectionMetrics() []prometheus.Collector {
+	return []prometheus.Collector{
+		gpbckpCollectionDurationMetric,
+		gpbckpCollectionLastSuccessTimeMetric,
+		gpbckpCollectionsMetric,
+		gpbckpErrorsMetric,
+		gpbckpUpMetric,
+		gpbckpSnapshotStaleMetric,
+		gpbckpCollectionRowsReadMetric,
+		gpbckpCollectionRowsFilteredMetric,
+		gpbckpCollectionSeriesMetric,
+		gpbckpSnapshotCollector,
+	}
+}
+
+// Set metrics for collection:
+//   - gpbackup_exporter_collection_duration_seconds
+//   - gpbackup_exporter_collection_last_success_timestamp_seconds
+//   - gpbackup_exporter_collections_total
+//   - gpbackup_exporter_errors_total
+//   - gpbackup_exporter_up
+//   - gpbackup_exporter_snapshot_stale
+//   - gpbackup_exporter_collection_rows_read
+//   - gpbackup_exporter_collection_rows_filtered
+//   - gpbackup_exporter_collection_series
+//   - gpbackup_exporter_history_snapshot_age_seconds
+//   - gpbackup_exporter_history_snapshot_size_bytes
+func setCollectionMetrics(cluster string, duration time.Duration, stats historyStats, series int, stale bool, err error) {
+	gpbckpCollectionDurationMetric.WithLabelValues(cluster).Set(duration.Seconds())
+	gpbckpCollectionsMetric.WithLabelValues(cluster).Inc()
+	gpbckpCollectionSeriesMetric.WithLabelValues(cluster).Set(float64(series))
+	// Metric is set even if there are no databases in history database,
+	// unlike gpbackup_exporter_status.
+	gpbckpUpMetric.WithLabelValues(cluster).Set(convertBoolToFloat64(err == nil))
+	gpbckpSnapshotStaleMetric.WithLabelValues(cluster).Set(convertBoolToFloat64(stale))
+	if err != nil {
+		gpbckpErrorsMetric.WithLabelValues(cluster, getErrorReason(err)).Inc()
+		return
+	}
+	gpbckpCollectionLastSuccessTimeMetric.WithLabelValues(cluster).SetToCurrentTime()
+	gpbckpCollectionRowsReadMetric.WithLabelValues(cluster).Set(float64(stats.rowsRead))
+	for filter, rows := range stats.rowsFiltered {
+		gpbckpCollectionRowsFilteredMetric.WithLabelValues(cluster, filter).Set(float64(rows))
+	}
+	if stats.snapshot != nil {
+		gpbckpSnapshotCollector.set(cluster, *stats.snapshot)
+	}
+}
@@ -0,0 +1,76 @@
+package gpbckpexporter
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+func TestSetCollectionMetrics(t *testing.T) {
+	templateMetrics := `# HELP gpbackup_exporter_collection_duration_seconds Duration of the last collection in seconds.
+# TYPE gpbackup_exporter_collection_duration_seconds gauge
+gpbackup_exporter_collection_duration_seconds{cluster="test"} 2
+# HELP gpbackup_exporter_collection_rows_filtered Number of backups filtered out by filter during the last successful collection.
+# TYPE gpbackup_exporter_collection_rows_filtered gauge
+gpbackup_exporter_collection_rows_filtered{cluster="test",filter="failed"} 2
+# HELP gpbackup_exporter_collection_rows_read Number of rows read from history file during the last successful collection.
+# TYPE gpbackup_exporter_collection_rows_read gauge
+gpbackup_exporter_collection_rows_read{cluster="test"} 10
+# HELP gpbackup_exporter_collection_series Number of metric series served after the last collection.
+# TYPE gpbackup_exporter_collection_series gauge
+gpbackup_exporter_collection_series{cluster="test"} 5
+# HELP gpbackup_exporter_collections_total Total number of collections.
+# TYPE gpbackup_exporter_collections_total counter
+gpbackup_exporter_collections_total{cluster="test"} 2
+# HELP gpbackup_exporter_errors_total Total number of errors during getting data from history database by reason.
+# TYPE gpbackup_exporter_errors_total counter
+gpbackup_exporter_errors_total{cluster="test",reason="database_locked"} 1
+# HELP gpbackup_exporter_snapshot_stale Whether metrics from the previous collection are served, because history database is busy.
+# TYPE gpbackup_exporter_snapshot_stale gauge
+gpbackup_exporter_snapshot_stale{cluster="test"} 1
+# HELP gpbackup_exporter_up Whether the last collection from history database was successful.
+# TYPE gpbackup_exporter_up gauge
+gpbackup_exporter_up{cluster="test"} 0
+`
+	resetCollectionMetrics()
+	defer resetCollectionMetrics()
+	setCollectionMetrics("test", time.Second, historyStats{rowsRead: 10, rowsFiltered: map[string]int{"failed": 2}}, 5, false, nil)
+	// Statistics of the last successful collection are kept after failed collection.
+	setCollectionMetrics("test", 2*time.Second, historyStats{}, 5, true, &historyError{errDatabaseLocked, errors.New("database is locked")})
+	// Last success timestamp is checked separately, because its value is changed.
+	lastSuccess := &dto.Metric{}
+	if err := gpbckpCollectionLastSuccessTimeMetric.WithLabelValues("test").Write(lastSuccess); err != nil {
+		t.Errorf("\nGet error during write metric:\n%v", err)
+	}
+	if lastSuccess.GetGauge().GetValue() == 0 {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\nnon-zero value", lastSuccess.GetGauge().GetValue())
+	}
+	gpbckpCollectionLastSuccessTimeMetric.Reset()
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(GetCollectionMetrics()...)
+	metricFamily, err := reg.Gather()
+	if err != nil {
+		t.Errorf("\nGet error during gather:\n%v", err)
+	}
+	out := &bytes.Buffer{}
+	for _, mf := range metricFamily {
+		if _, err := expfmt.MetricFamilyToText(out, mf); err != nil {
+			panic(err)
+		}
+	}
+	if templateMetrics != out.String() {
+		t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", out.String(), templateMetrics)
+	}
+}
+
+// Reset metrics for collections, they are global and not reset during collection.
+func resetCollectionMetrics() {
+	for _, collector := range GetCollectionMetrics() {
+		collector.(interface{ Reset() }).Reset()
+	}
+}
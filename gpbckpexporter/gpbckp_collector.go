@@ -0,0 +1,150 @@
+package gpbckpexporter
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// backupCollector implements prometheus.Collector.
+// Metrics are served from immutable snapshots, which are atomically swapped
+// at the end of each collection. So every scrape sees either the complete
+// previous state or the complete new one.
+// Each history source (cluster) has its own snapshot.
+type backupCollector struct {
+	snapshots atomic.Pointer[map[string][]prometheus.Metric]
+}
+
+var (
+	gpbckpCollector = &backupCollector{}
+	// Collections are serialized, because metric vectors below are shared
+	// between collections and used as a scratch area to build snapshot.
+	collectMutex sync.Mutex
+)
+
+// All metric vectors, whose values are set up during collection.
+func collectedMetricVecs() []*prometheus.GaugeVec {
+	return []*prometheus.GaugeVec{
+		gpbckpBackupStatusMetric,
+		gpbckpBackupDataDeletedStatusMetric,
+		gpbckpBackupInfoMetric,
+		gpbckpBackupDurationMetric,
+		gpbckpBackupInProgressMetric,
+		gpbckpBackupInProgressElapsedSecondsMetric,
+		gpbckpBackupInProgressStuckMetric,
+		gpbckpBackupDeletionStateBackupsMetric,
+		gpbckpBackupDeletionAgeSecondsMetric,
+		gpbckpBackupSinceLastCompletionSecondsMetric,
+		gpbckpDatabaseRPOSecondsMetric,
+		gpbckpDatabaseBackupMissingMetric,
+		gpbckpDatabaseBackupCoverageMetric,
+		gpbckpBackupSLADeadlineSecondsMetric,
+		gpbckpBackupSLAViolatedMetric,
+		gpbckpBackupRestoreChainLengthMetric,
+		gpbckpBackupBaseFullTimestampMetric,
+		gpbckpBackupBaseFullAgeSecondsMetric,
+		gpbckpBackupDependentBackupsMetric,
+		gpbckpBackupIncrementalChainLengthMetric,
+		gpbckpBackupRestorableMetric,
+		gpbckpBackupUnrestorableMetric,
+		gpbckpExporterStatusMetric,
+	}
+}
+
+// Create new metric vectors with the same options as collected metric vectors.
+// Keys are global metric vectors, values are new ones.
+func newMetricVecs() map[*prometheus.GaugeVec]*prometheus.GaugeVec {
+	return map[*prometheus.GaugeVec]*prometheus.GaugeVec{
+		gpbckpBackupStatusMetric:                     newBackupStatusMetric(),
+		gpbckpBackupDataDeletedStatusMetric:          newBackupDataDeletedStatusMetric(),
+		gpbckpBackupInfoMetric:                       newBackupInfoMetric(),
+		gpbckpBackupDurationMetric:                   newBackupDurationMetric(),
+		gpbckpBackupInProgressMetric:                 newBackupInProgressMetric(),
+		gpbckpBackupInProgressElapsedSecondsMetric:   newBackupInProgressElapsedSecondsMetric(),
+		gpbckpBackupInProgressStuckMetric:            newBackupInProgressStuckMetric(),
+		gpbckpBackupDeletionStateBackupsMetric:       newBackupDeletionStateBackupsMetric(),
+		gpbckpBackupDeletionAgeSecondsMetric:         newBackupDeletionAgeSecondsMetric(),
+		gpbckpBackupSinceLastCompletionSecondsMetric: newBackupSinceLastCompletionSecondsMetric(),
+		gpbckpDatabaseRPOSecondsMetric:               newDatabaseRPOSecondsMetric(),
+		gpbckpDatabaseBackupMissingMetric:            newDatabaseBackupMissingMetric(),
+		gpbckpDatabaseBackupCoverageMetric:           newDatabaseBackupCoverageMetric(),
+		gpbckpBackupSLADeadlineSecondsMetric:         newBackupSLADeadlineSecondsMetric(),
+		gpbckpBackupSLAViolatedMetric:                newBackupSLAViolatedMetric(),
+		gpbckpBackupRestoreChainLengthMetric:         newBackupRestoreChainLengthMetric(),
+		gpbckpBackupBaseFullTimestampMetric:          newBackupBaseFullTimestampMetric(),
+		gpbckpBackupBaseFullAgeSecondsMetric:         newBackupBaseFullAgeSecondsMetric(),
+		gpbckpBackupDependentBackupsMetric:           newBackupDependentBackupsMetric(),
+		gpbckpBackupIncrementalChainLengthMetric:     newBackupIncrementalChainLengthMetric(),
+		gpbckpBackupRestorableMetric:                 newBackupRestorableMetric(),
+		gpbckpBackupUnrestorableMetric:               newBackupUnrestorableMetric(),
+		gpbckpExporterStatusMetric:                   newExporterStatusMetric(),
+	}
+}
+
+// GetCollector returns collector, which serves metrics
+// from the last completed collection.
+func GetCollector() prometheus.Collector {
+	return gpbckpCollector
+}
+
+// Describe implements prometheus.Collector.
+func (c *backupCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, metricVec := range collectedMetricVecs() {
+		metricVec.Describe(ch)
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *backupCollector) Collect(ch chan<- prometheus.Metric) {
+	snapshots := c.snapshots.Load()
+	if snapshots == nil {
+		return
+	}
+	for _, snapshot := range *snapshots {
+		for _, metric := range snapshot {
+			ch <- metric
+		}
+	}
+}
+
+// Replace current snapshot for cluster with the new one.
+// Updates are serialized via collectMutex, so snapshots map is copied without extra locking.
+func (c *backupCollector) update(cluster string, snapshot []prometheus.Metric) {
+	snapshots := make(map[string][]prometheus.Metric)
+	if current := c.snapshots.Load(); current != nil {
+		for name, metrics := range *current {
+			snapshots[name] = metrics
+		}
+	}
+	snapshots[cluster] = snapshot
+	c.snapshots.Store(&snapshots)
+}
+
+// Get current snapshot for cluster.
+func (c *backupCollector) snapshot(cluster string) ([]prometheus.Metric, bool) {
+	snapshots := c.snapshots.Load()
+	if snapshots == nil {
+		return nil, false
+	}
+	snapshot, ok := (*snapshots)[cluster]
+	return snapshot, ok
+}
+
+// Get current values of all metric vectors.
+// Metric vectors are reset at the beginning of each collection,
+// so returned metrics are never changed after that.
+func snapshotMetrics() []prometheus.Metric {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		for _, metricVec := range collectedMetricVecs() {
+			metricVec.Collect(ch)
+		}
+		close(ch)
+	}()
+	snapshot := make([]prometheus.Metric, 0)
+	for metric := range ch {
+		snapshot = append(snapshot, metric)
+	}
+	return snapshot
+}
@@ -0,0 +1,105 @@
+package gpbckpexporter
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+func TestBackupCollector(t *testing.T) {
+	templateMetrics := `# HELP gpbackup_exporter_status gpbackup exporter get data status.
+# TYPE gpbackup_exporter_status gauge
+gpbackup_exporter_status{cluster="",database_name="test"} 1
+`
+	tests := []struct {
+		name     string
+		dbStatus dbStatusMap
+		testText string
+	}{
+		{
+			"SnapshotNotChangedAfterReset",
+			dbStatusMap{"test": true},
+			templateMetrics,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &backupCollector{}
+			resetMetrics()
+			getExporterStatusMetrics("", tt.dbStatus, setUpMetricValue, getLogger())
+			collector.update("", snapshotMetrics())
+			// Metric vectors are reset at the beginning of next collection,
+			// but collector must serve previous snapshot.
+			resetMetrics()
+			reg := prometheus.NewRegistry()
+			reg.MustRegister(collector)
+			metricFamily, err := reg.Gather()
+			if err != nil {
+				fmt.Println(err)
+			}
+			out := &bytes.Buffer{}
+			for _, mf := range metricFamily {
+				if _, err := expfmt.MetricFamilyToText(out, mf); err != nil {
+					panic(err)
+				}
+			}
+			if tt.testText != out.String() {
+				t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", tt.testText, out.String())
+			}
+		})
+	}
+}
+
+func TestBackupCollectorEmpty(t *testing.T) {
+	collector := &backupCollector{}
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collector)
+	metricFamily, err := reg.Gather()
+	if err != nil {
+		t.Errorf("\nGet error during gather:\n%v", err)
+	}
+	if len(metricFamily) != 0 {
+		t.Errorf("\nVariables do not match:\n%d\nwant:\n%d", len(metricFamily), 0)
+	}
+}
+
+func TestBackupCollectorClusters(t *testing.T) {
+	templateMetrics := `# HELP gpbackup_exporter_status gpbackup exporter get data status.
+# TYPE gpbackup_exporter_status gauge
+gpbackup_exporter_status{cluster="cluster1",database_name="test"} 0
+gpbackup_exporter_status{cluster="cluster2",database_name="test"} 1
+`
+	collector := &backupCollector{}
+	updates := []struct {
+		cluster  string
+		dbStatus dbStatusMap
+	}{
+		{"cluster1", dbStatusMap{"test": true}},
+		{"cluster2", dbStatusMap{"test": true}},
+		// Update of one cluster doesn't affect snapshot of another one.
+		{"cluster1", dbStatusMap{"test": false}},
+	}
+	for _, u := range updates {
+		resetMetrics()
+		getExporterStatusMetrics(u.cluster, u.dbStatus, setUpMetricValue, getLogger())
+		collector.update(u.cluster, snapshotMetrics())
+	}
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collector)
+	metricFamily, err := reg.Gather()
+	if err != nil {
+		t.Errorf("\nGet error during gather:\n%v", err)
+	}
+	out := &bytes.Buffer{}
+	for _, mf := range metricFamily {
+		if _, err := expfmt.MetricFamilyToText(out, mf); err != nil {
+			panic(err)
+		}
+	}
+	if templateMetrics != out.String() {
+		t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", out.String(), templateMetrics)
+	}
+}
@@ -0,0 +1,276 @@
+package gpbckpexporter
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+// CollectConfig contains settings for collecting metrics,
+// which can be changed without exporter restart.
+type CollectConfig struct {
+	BackupType          string
+	CollectDeleted      bool
+	CollectFailed       bool
+	DBInclude           []string
+	DBExclude           []string
+	DBExpected          []string
+	CatalogDB           string
+	CatalogTimeout      int
+	CollectDepth        int
+	InProgressThreshold int
+	SLA                 SLAConfig
+}
+
+// SLAPolicy contains maximum intervals between successful backups by backup type.
+// Zero interval means that there are no expectations for backup type.
+type SLAPolicy map[string]model.Duration
+
+// SLAConfig contains expectations for backups of databases.
+// Policy for database overrides default policy for the same backup types,
+// expectations for other backup types are taken from default policy.
+type SLAConfig struct {
+	Default   SLAPolicy            `yaml:"default"`
+	Databases map[string]SLAPolicy `yaml:"databases"`
+}
+
+// HistorySource is gpbackup history file of one cluster.
+// Name is used as value for cluster label.
+type HistorySource struct {
+	Name        string `yaml:"name"`
+	HistoryFile string `yaml:"history_file"`
+}
+
+// ParseHistorySource parses history source in format '<name>=<history file path>'.
+func ParseHistorySource(value string) (HistorySource, error) {
+	name, historyFile, ok := strings.Cut(value, "=")
+	if !ok {
+		return HistorySource{}, fmt.Errorf("invalid history source %q, expected format: <name>=<history file path>", value)
+	}
+	return HistorySource{Name: name, HistoryFile: historyFile}, nil
+}
+
+// ValidateHistorySources checks that all sources have names and history files,
+// and names are unique.
+func ValidateHistorySources(sources []HistorySource) error {
+	names := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		if source.Name == "" {
+			return fmt.Errorf("empty name for history source with file %q", source.HistoryFile)
+		}
+		if source.HistoryFile == "" {
+			return fmt.Errorf("empty history file for history source %q", source.Name)
+		}
+		if names[source.Name] {
+			return fmt.Errorf("duplicate history source name %q", source.Name)
+		}
+		names[source.Name] = true
+	}
+	return nil
+}
+
+// ValidateCatalogDB checks that catalog is used with no more than one history source.
+// Catalog is read from the coordinator specified via environment variables,
+// so with several sources all of them would expect databases of the same cluster.
+func ValidateCatalogDB(catalogDB string, sources []HistorySource) error {
+	if catalogDB != "" && len(sources) > 1 {
+		return fmt.Errorf("catalog can't be used with several history sources, got %d sources", len(sources))
+	}
+	return nil
+}
+
+// Validate checks settings for collecting metrics.
+// Errors contain keys of settings in configuration file.
+func (c CollectConfig) Validate() error {
+	if !validBackupType(c.BackupType) {
+		return fmt.Errorf("backup_type: invalid value %q", c.BackupType)
+	}
+	if c.InProgressThreshold < 0 {
+		return fmt.Errorf("in_progress_threshold: value must not be negative, got %d", c.InProgressThreshold)
+	}
+	if c.CatalogTimeout < 0 {
+		return fmt.Errorf("catalog_timeout: value must not be negative, got %d", c.CatalogTimeout)
+	}
+	if err := c.SLA.Validate(); err != nil {
+		return fmt.Errorf("sla: %w", err)
+	}
+	return nil
+}
+
+// Validate checks that policies contain only known backup types.
+func (c SLAConfig) Validate() error {
+	if err := c.Default.validate(); err != nil {
+		return fmt.Errorf("default: %w", err)
+	}
+	for db, policy := range c.Databases {
+		if db == "" {
+			return errors.New("empty database name")
+		}
+		if err := policy.validate(); err != nil {
+			return fmt.Errorf("database %q: %w", db, err)
+		}
+	}
+	return nil
+}
+
+func (p SLAPolicy) validate() error {
+	for backupType := range p {
+		if backupType == "" || !validBackupType(backupType) {
+			return fmt.Errorf("invalid backup type: %q", backupType)
+		}
+	}
+	return nil
+}
+
+// Get maximum intervals between successful backups for database by backup type.
+// Backup types with zero interval are skipped.
+func (c SLAConfig) policy(db string) SLAPolicy {
+	policy := make(SLAPolicy)
+	for backupType, interval := range c.Default {
+		policy[backupType] = interval
+	}
+	for backupType, interval := range c.Databases[db] {
+		policy[backupType] = interval
+	}
+	for backupType, interval := range policy {
+		if interval == 0 {
+			delete(policy, backupType)
+		}
+	}
+	return policy
+}
+
+// Check that SLA isn't configured.
+func (c SLAConfig) empty() bool {
+	return len(c.Default) == 0 && len(c.Databases) == 0
+}
+
+// Check backup type filter value, empty value means all backup types.
+func validBackupType(backupType string) bool {
+	switch backupType {
+	case "", gpbckpconfig.BackupTypeFull, gpbckpconfig.BackupTypeIncremental, gpbckpconfig.BackupTypeDataOnly, gpbckpconfig.BackupTypeMetadataOnly:
+		return true
+	}
+	return false
+}
+
+// ConfigLoadFunc returns new settings for collecting metrics.
+type ConfigLoadFunc func() (CollectConfig, error)
+
+// ConfigReloader keeps current settings for collecting metrics.
+// New settings are loaded and validated on reload,
+// current settings are replaced only if there are no errors.
+type ConfigReloader struct {
+	load   ConfigLoadFunc
+	config atomic.Pointer[CollectConfig]
+	// Reloads are serialized.
+	mu sync.Mutex
+}
+
+var (
+	gpbckpConfigLastReloadSuccessMetric = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "gpbackup_exporter_config_last_reload_successful",
+		Help: "gpbackup exporter last configuration reload status.",
+	})
+	gpbckpConfigLastReloadSuccessTimeMetric = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "gpbackup_exporter_config_last_reload_success_timestamp_seconds",
+		Help: "Timestamp of the last successful configuration reload.",
+	})
+	// Reload endpoint is disabled, if it's nil.
+	configReloader *ConfigReloader
+)
+
+// Endpoint for configuration reload.
+const reloadEndpoint = "/-/reload"
+
+// NewConfigReloader loads initial settings for collecting metrics.
+func NewConfigReloader(load ConfigLoadFunc) (*ConfigReloader, error) {
+	config, err := load()
+	if err == nil {
+		err = config.Validate()
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := &ConfigReloader{load: load}
+	r.config.Store(&config)
+	gpbckpConfigLastReloadSuccessMetric.Set(1)
+	gpbckpConfigLastReloadSuccessTimeMetric.SetToCurrentTime()
+	return r, nil
+}
+
+// Config returns current settings for collecting metrics.
+func (r *ConfigReloader) Config() CollectConfig {
+	return *r.config.Load()
+}
+
+// Reload loads new settings for collecting metrics.
+// New settings are used starting from the next collection.
+func (r *ConfigReloader) Reload(logger *slog.Logger) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	config, err := r.load()
+	if err == nil {
+		err = config.Validate()
+	}
+	if err != nil {
+		gpbckpConfigLastReloadSuccessMetric.Set(0)
+		return err
+	}
+	r.config.Store(&config)
+	gpbckpConfigLastReloadSuccessMetric.Set(1)
+	gpbckpConfigLastReloadSuccessTimeMetric.SetToCurrentTime()
+	logger.Info(
+		"Configuration reloaded",
+		"backup_type", config.BackupType,
+		"deleted", config.CollectDeleted,
+		"failed", config.CollectFailed,
+		"db_include", config.DBInclude,
+		"db_exclude", config.DBExclude,
+		"db_expected", config.DBExpected,
+		"catalog_db", config.CatalogDB,
+		"catalog_timeout", config.CatalogTimeout,
+		"depth", config.CollectDepth,
+		"in_progress_threshold", config.InProgressThreshold,
+		"sla", config.SLA,
+	)
+	return nil
+}
+
+// GetConfigReloadMetrics returns metrics about configuration reload.
+func GetConfigReloadMetrics() []prometheus.Collector {
+	return []prometheus.Collector{
+		gpbckpConfigLastReloadSuccessMetric,
+		gpbckpConfigLastReloadSuccessTimeMetric,
+	}
+}
+
+// SetReloadEndpoint enables HTTP endpoint for configuration reload.
+func SetReloadEndpoint(reloader *ConfigReloader) {
+	configReloader = reloader
+}
+
+// Handler for configuration reload via HTTP.
+// Only POST method is allowed.
+func reloadHandler(reloader *ConfigReloader, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Only POST requests allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := reloader.Reload(logger); err != nil {
+			logger.Error("Reload configuration via HTTP failed", "err", err)
+			http.Error(w, fmt.Sprintf("failed to reload config: %s", err), http.StatusInternalServerError)
+			return
+		}
+	})
+}
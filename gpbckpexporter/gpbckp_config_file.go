@@ -0,0 +1,166 @@
+package gpbckpexporter
+
+import (
+	"fmt"
+	"os"
+
+	"go.yaml.in/yaml/v2"
+)
+
+// ExporterConfig is the content of exporter configuration file.
+// All fields are optional, nil value means that the setting isn't specified in file.
+type ExporterConfig struct {
+	GPBackup GPBackupFileConfig `yaml:"gpbackup"`
+	Collect  CollectFileConfig  `yaml:"collect"`
+	Probe    ProbeFileConfig    `yaml:"probe"`
+	SLA      SLAConfig          `yaml:"sla"`
+}
+
+// GPBackupFileConfig contains settings for gpbackup.* flags.
+type GPBackupFileConfig struct {
+	HistoryFile    *string          `yaml:"history_file"`
+	HistorySources *[]HistorySource `yaml:"history_sources"`
+	// Settings for collecting metrics, they are reloaded.
+	CollectSettings `yaml:",inline"`
+	// Settings for access to history database, they aren't reloaded.
+	BusyTimeout      *int    `yaml:"busy_timeout"`
+	LockRetries      *int    `yaml:"lock_retries"`
+	LockRetryBackoff *int    `yaml:"lock_retry_backoff"`
+	SnapshotCopy     *bool   `yaml:"snapshot_copy"`
+	SnapshotDir      *string `yaml:"snapshot_dir"`
+	// File for deletion states of backups, it isn't reloaded.
+	DeletionStateFile *string `yaml:"deletion_state_file"`
+}
+
+// CollectSettings contains settings for collecting metrics.
+// The same keys are used in gpbackup section and for probe targets.
+type CollectSettings struct {
+	DBInclude           *[]string `yaml:"db_include"`
+	DBExclude           *[]string `yaml:"db_exclude"`
+	DBExpected          *[]string `yaml:"db_expected"`
+	CatalogDB           *string   `yaml:"catalog_db"`
+	CatalogTimeout      *int      `yaml:"catalog_timeout"`
+	BackupType          *string   `yaml:"backup_type"`
+	CollectDeleted      *bool     `yaml:"collect_deleted"`
+	CollectFailed       *bool     `yaml:"collect_failed"`
+	InProgressThreshold *int      `yaml:"in_progress_threshold"`
+}
+
+// CollectFileConfig contains settings for collect.* flags.
+type CollectFileConfig struct {
+	Interval      *int  `yaml:"interval"`
+	Depth         *int  `yaml:"depth"`
+	Watch         *bool `yaml:"watch"`
+	WatchDebounce *int  `yaml:"watch_debounce"`
+}
+
+// ProbeFileConfig contains targets for probe endpoint.
+type ProbeFileConfig struct {
+	Targets []ProbeTargetFileConfig `yaml:"targets"`
+}
+
+// ProbeTargetFileConfig contains settings for probe target.
+// Settings, which aren't specified, have default values.
+type ProbeTargetFileConfig struct {
+	Name            string `yaml:"name"`
+	HistoryFile     string `yaml:"history_file"`
+	CollectSettings `yaml:",inline"`
+	Depth           *int      `yaml:"depth"`
+	SLA             SLAConfig `yaml:"sla"`
+}
+
+// ProbeTargets returns probe targets with settings for collecting metrics.
+func (c ProbeFileConfig) ProbeTargets() []ProbeTarget {
+	targets := make([]ProbeTarget, 0, len(c.Targets))
+	for _, target := range c.Targets {
+		targets = append(targets, target.probeTarget())
+	}
+	return targets
+}
+
+func (t ProbeTargetFileConfig) probeTarget() ProbeTarget {
+	target := ProbeTarget{Name: t.Name, HistoryFile: t.HistoryFile}
+	t.CollectSettings.apply(&target.CollectConfig)
+	if t.Depth != nil {
+		target.CollectDepth = *t.Depth
+	}
+	target.SLA = t.SLA
+	return target
+}
+
+// Set values, which are specified in configuration file.
+func (s CollectSettings) apply(config *CollectConfig) {
+	setValue(&config.DBInclude, s.DBInclude)
+	setValue(&config.DBExclude, s.DBExclude)
+	setValue(&config.DBExpected, s.DBExpected)
+	setValue(&config.CatalogDB, s.CatalogDB)
+	setValue(&config.CatalogTimeout, s.CatalogTimeout)
+	setValue(&config.BackupType, s.BackupType)
+	setValue(&config.CollectDeleted, s.CollectDeleted)
+	setValue(&config.CollectFailed, s.CollectFailed)
+	setValue(&config.InProgressThreshold, s.InProgressThreshold)
+}
+
+func setValue[T any](value, fileValue *T) {
+	if fileValue != nil {
+		*value = *fileValue
+	}
+}
+
+// LoadConfigFile reads and validates exporter configuration file.
+// Unknown keys are not allowed.
+func LoadConfigFile(file string) (ExporterConfig, error) {
+	var config ExporterConfig
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return config, err
+	}
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return config, fmt.Errorf("parse config file %s: %w", file, err)
+	}
+	if err := config.validate(); err != nil {
+		return config, fmt.Errorf("invalid config file %s: %w", file, err)
+	}
+	return config, nil
+}
+
+// Check values, which are specified in configuration file.
+func (c ExporterConfig) validate() error {
+	// Settings for collecting metrics are checked in the same way
+	// as after applying command line flags and as for probe targets.
+	var collectConfig CollectConfig
+	c.GPBackup.CollectSettings.apply(&collectConfig)
+	if err := collectConfig.Validate(); err != nil {
+		return fmt.Errorf("gpbackup.%w", err)
+	}
+	if c.GPBackup.HistorySources != nil {
+		if err := ValidateHistorySources(*c.GPBackup.HistorySources); err != nil {
+			return fmt.Errorf("gpbackup.history_sources: %w", err)
+		}
+		if err := ValidateCatalogDB(collectConfig.CatalogDB, *c.GPBackup.HistorySources); err != nil {
+			return fmt.Errorf("gpbackup.catalog_db: %w", err)
+		}
+	}
+	if err := c.SLA.Validate(); err != nil {
+		return fmt.Errorf("sla: %w", err)
+	}
+	if err := ValidateProbeTargets(c.Probe.ProbeTargets()); err != nil {
+		return fmt.Errorf("probe.targets: %w", err)
+	}
+	if c.GPBackup.BusyTimeout != nil && *c.GPBackup.BusyTimeout < 0 {
+		return fmt.Errorf("gpbackup.busy_timeout: value must not be negative, got %d", *c.GPBackup.BusyTimeout)
+	}
+	if c.GPBackup.LockRetries != nil && *c.GPBackup.LockRetries < 0 {
+		return fmt.Errorf("gpbackup.lock_retries: value must not be negative, got %d", *c.GPBackup.LockRetries)
+	}
+	if c.GPBackup.LockRetryBackoff != nil && *c.GPBackup.LockRetryBackoff < 0 {
+		return fmt.Errorf("gpbackup.lock_retry_backoff: value must not be negative, got %d", *c.GPBackup.LockRetryBackoff)
+	}
+	if c.Collect.Interval != nil && *c.Collect.Interval <= 0 {
+		return fmt.Errorf("collect.interval: value must be positive, got %d", *c.Collect.Interval)
+	}
+	if c.Collect.WatchDebounce != nil && *c.Collect.WatchDebounce < 0 {
+		return fmt.Errorf("collect.watch_debounce: value must not be negative, got %d", *c.Collect.WatchDebounce)
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package gpbckpexporter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	historyFile := "/data/master/gpseg-1/gpbackup_history.db"
+	backupType := "full"
+	collectDeleted := true
+	interval := 300
+	negativeDepth := -1
+	depth := 14
+	dbExclude := []string{"test"}
+	dbInclude := []string{"test1", "test2"}
+	dbExpected := []string{"test1"}
+	tests := []struct {
+		name    string
+		data    string
+		want    ExporterConfig
+		wantErr string
+	}{
+		{
+			"FullConfig",
+			`gpbackup:
+  history_file: /data/master/gpseg-1/gpbackup_history.db
+  db_include: [test1, test2]
+  db_expected: [test1]
+  backup_type: full
+  collect_deleted: true
+collect:
+  interval: 300
+`,
+			ExporterConfig{
+				GPBackup: GPBackupFileConfig{
+					HistoryFile: &historyFile,
+					CollectSettings: CollectSettings{
+						DBInclude:      &dbInclude,
+						DBExpected:     &dbExpected,
+						BackupType:     &backupType,
+						CollectDeleted: &collectDeleted,
+					},
+				},
+				Collect: CollectFileConfig{
+					Interval: &interval,
+				},
+			},
+			"",
+		},
+		{
+			"EmptyConfig",
+			"",
+			ExporterConfig{},
+			"",
+		},
+		{
+			"UnknownKey",
+			`gpbackup:
+  history_fil: /data/master/gpseg-1/gpbackup_history.db
+`,
+			ExporterConfig{},
+			"field history_fil not found",
+		},
+		{
+			"HistorySources",
+			`gpbackup:
+  history_sources:
+    - name: cluster1
+      history_file: /data/cluster1/gpbackup_history.db
+`,
+			ExporterConfig{
+				GPBackup: GPBackupFileConfig{
+					HistorySources: &[]HistorySource{{"cluster1", "/data/cluster1/gpbackup_history.db"}},
+				},
+			},
+			"",
+		},
+		{
+			"DuplicateHistorySources",
+			`gpbackup:
+  history_sources:
+    - name: cluster1
+      history_file: /data/cluster1/gpbackup_history.db
+    - name: cluster1
+      history_file: /data/cluster2/gpbackup_history.db
+`,
+			ExporterConfig{},
+			"gpbackup.history_sources",
+		},
+		{
+			"CatalogWithSeveralHistorySources",
+			`gpbackup:
+  catalog_db: postgres
+  history_sources:
+    - name: cluster1
+      history_file: /data/cluster1/gpbackup_history.db
+    - name: cluster2
+      history_file: /data/cluster2/gpbackup_history.db
+`,
+			ExporterConfig{},
+			"gpbackup.catalog_db",
+		},
+		{
+			"ProbeTargets",
+			`probe:
+  targets:
+    - name: cluster1
+      history_file: /data/cluster1/gpbackup_history.db
+      backup_type: full
+      db_exclude: [test]
+      depth: 14
+`,
+			ExporterConfig{
+				Probe: ProbeFileConfig{
+					Targets: []ProbeTargetFileConfig{
+						{
+							Name:        "cluster1",
+							HistoryFile: "/data/cluster1/gpbackup_history.db",
+							CollectSettings: CollectSettings{
+								BackupType: &backupType,
+								DBExclude:  &dbExclude,
+							},
+							Depth: &depth,
+						},
+					},
+				},
+			},
+			"",
+		},
+		{
+			"InvalidProbeTarget",
+			`probe:
+  targets:
+    - name: cluster1
+      history_file: /data/cluster1/gpbackup_history.db
+      backup_type: diff
+`,
+			ExporterConfig{},
+			"probe.targets",
+		},
+		{
+			"InvalidBackupType",
+			`gpbackup:
+  backup_type: diff
+`,
+			ExporterConfig{},
+			"gpbackup.backup_type",
+		},
+		{
+			"InvalidLockRetries",
+			`gpbackup:
+  lock_retries: -1
+`,
+			ExporterConfig{},
+			"gpbackup.lock_retries",
+		},
+		{
+			"InvalidInterval",
+			`collect:
+  interval: 0
+`,
+			ExporterConfig{},
+			"collect.interval",
+		},
+		{
+			"NegativeDepth",
+			`collect:
+  depth: -1
+`,
+			ExporterConfig{
+				Collect: CollectFileConfig{
+					Depth: &negativeDepth,
+				},
+			},
+			"",
+		},
+		{
+			"InvalidInProgressThreshold",
+			`gpbackup:
+  in_progress_threshold: -1
+`,
+			ExporterConfig{},
+			"gpbackup.in_progress_threshold",
+		},
+		{
+			"InvalidCatalogTimeout",
+			`gpbackup:
+  catalog_timeout: -1
+`,
+			ExporterConfig{},
+			"gpbackup.catalog_timeout",
+		},
+		{
+			"SLA",
+			`sla:
+  default:
+    full: 7d
+  databases:
+    test1:
+      full: 1d
+      incremental: 6h
+`,
+			ExporterConfig{
+				SLA: SLAConfig{
+					Default: SLAPolicy{"full": model.Duration(7 * 24 * time.Hour)},
+					Databases: map[string]SLAPolicy{
+						"test1": {"full": model.Duration(24 * time.Hour), "incremental": model.Duration(6 * time.Hour)},
+					},
+				},
+			},
+			"",
+		},
+		{
+			"InvalidSLA",
+			`sla:
+  default:
+    diff: 1d
+`,
+			ExporterConfig{},
+			"sla",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configFile := filepath.Join(t.TempDir(), "gpbackup_exporter.yml")
+			if err := os.WriteFile(configFile, []byte(tt.data), 0600); err != nil {
+				t.Fatalf("Failed to create config file: %v", err)
+			}
+			got, err := LoadConfigFile(configFile)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("\nVariables do not match:\n%v\nwant error with:\n%s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("\nGet error during load config file:\n%v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nVariables do not match:\n%+v\nwant:\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbeFileConfigTargets(t *testing.T) {
+	backupType := "full"
+	dbExclude := []string{"test"}
+	depth := 14
+	config := ProbeFileConfig{
+		Targets: []ProbeTargetFileConfig{
+			{
+				Name:        "cluster1",
+				HistoryFile: "/data/cluster1/gpbackup_history.db",
+				CollectSettings: CollectSettings{
+					BackupType: &backupType,
+					DBExclude:  &dbExclude,
+				},
+				Depth: &depth,
+				SLA:   SLAConfig{Default: SLAPolicy{"full": model.Duration(24 * time.Hour)}},
+			},
+		},
+	}
+	want := []ProbeTarget{
+		{
+			Name:        "cluster1",
+			HistoryFile: "/data/cluster1/gpbackup_history.db",
+			CollectConfig: CollectConfig{
+				BackupType:   "full",
+				DBExclude:    []string{"test"},
+				CollectDepth: 14,
+				SLA:          SLAConfig{Default: SLAPolicy{"full": model.Duration(24 * time.Hour)}},
+			},
+		},
+	}
+	if got := config.ProbeTargets(); !reflect.DeepEqual(got, want) {
+		t.Errorf("\nVariables do not match:\n%+v\nwant:\n%+v", got, want)
+	}
+}
+
+func TestLoadConfigFileNoFile(t *testing.T) {
+	if _, err := LoadConfigFile("/nonexistent/path/to/gpbackup_exporter.yml"); err == nil {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\nerror", err)
+	}
+}
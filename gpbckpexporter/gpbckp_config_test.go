@@ -0,0 +1,224 @@
+package gpbckpexporter
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+)
+
+func TestCollectConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  CollectConfig
+		wantErr bool
+	}{
+		{"EmptyConfig", CollectConfig{}, false},
+		{"ValidConfig", CollectConfig{BackupType: "metadata-only", CollectDepth: 14}, false},
+		{"InvalidBackupType", CollectConfig{BackupType: "diff"}, true},
+		// The same as zero depth, collection depth isn't limited.
+		{"NegativeDepth", CollectConfig{CollectDepth: -1}, false},
+		{"InvalidInProgressThreshold", CollectConfig{InProgressThreshold: -1}, true},
+		{"InvalidCatalogTimeout", CollectConfig{CatalogTimeout: -1}, true},
+		{"ValidSLA", CollectConfig{SLA: SLAConfig{
+			Default:   SLAPolicy{"full": model.Duration(24 * time.Hour)},
+			Databases: map[string]SLAPolicy{"test": {"incremental": model.Duration(time.Hour)}},
+		}}, false},
+		{"InvalidSLABackupType", CollectConfig{SLA: SLAConfig{Default: SLAPolicy{"diff": model.Duration(time.Hour)}}}, true},
+		{"EmptySLABackupType", CollectConfig{SLA: SLAConfig{Default: SLAPolicy{"": model.Duration(time.Hour)}}}, true},
+		{"EmptySLADatabase", CollectConfig{SLA: SLAConfig{Databases: map[string]SLAPolicy{"": {"full": model.Duration(time.Hour)}}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("\nVariables do not match:\n%v\nwantErr:\n%v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSLAConfigPolicy(t *testing.T) {
+	sla := SLAConfig{
+		Default: SLAPolicy{
+			"full":          model.Duration(7 * 24 * time.Hour),
+			"metadata-only": model.Duration(24 * time.Hour),
+		},
+		Databases: map[string]SLAPolicy{
+			"test1": {"full": model.Duration(24 * time.Hour), "incremental": model.Duration(time.Hour)},
+			"test2": {"metadata-only": 0},
+		},
+	}
+	tests := []struct {
+		name string
+		db   string
+		want SLAPolicy
+	}{
+		{"DefaultPolicy", "test", SLAPolicy{
+			"full":          model.Duration(7 * 24 * time.Hour),
+			"metadata-only": model.Duration(24 * time.Hour),
+		}},
+		{"OverridePolicy", "test1", SLAPolicy{
+			"full":          model.Duration(24 * time.Hour),
+			"incremental":   model.Duration(time.Hour),
+			"metadata-only": model.Duration(24 * time.Hour),
+		}},
+		{"DisabledBackupType", "test2", SLAPolicy{
+			"full": model.Duration(7 * 24 * time.Hour),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sla.policy(tt.db); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigReloader(t *testing.T) {
+	configs := []CollectConfig{
+		{BackupType: "full"},
+		{BackupType: "incremental", DBInclude: []string{"test"}},
+		{BackupType: "diff"},
+	}
+	var (
+		current int
+		loadErr error
+	)
+	load := func() (CollectConfig, error) {
+		return configs[current], loadErr
+	}
+	reloader, err := NewConfigReloader(load)
+	if err != nil {
+		t.Fatalf("\nGet error during create reloader:\n%v", err)
+	}
+	tests := []struct {
+		name        string
+		config      int
+		loadErr     error
+		wantErr     bool
+		wantType    string
+		wantSuccess float64
+	}{
+		{"ValidConfig", 1, nil, false, "incremental", 1},
+		{"InvalidConfig", 2, nil, true, "incremental", 0},
+		{"LoadError", 0, errors.New("load error"), true, "incremental", 0},
+		{"ValidConfigAfterErrors", 0, nil, false, "full", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, loadErr = tt.config, tt.loadErr
+			if err := reloader.Reload(getLogger()); (err != nil) != tt.wantErr {
+				t.Errorf("\nVariables do not match:\n%v\nwantErr:\n%v", err, tt.wantErr)
+			}
+			if got := reloader.Config().BackupType; got != tt.wantType {
+				t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", got, tt.wantType)
+			}
+			metric := &dto.Metric{}
+			if err := gpbckpConfigLastReloadSuccessMetric.Write(metric); err != nil {
+				t.Fatalf("\nGet error during write metric:\n%v", err)
+			}
+			if got := metric.GetGauge().GetValue(); got != tt.wantSuccess {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.wantSuccess)
+			}
+		})
+	}
+}
+
+func TestReloadHandler(t *testing.T) {
+	var loadErr error
+	reloader, err := NewConfigReloader(func() (CollectConfig, error) {
+		return CollectConfig{}, loadErr
+	})
+	if err != nil {
+		t.Fatalf("\nGet error during create reloader:\n%v", err)
+	}
+	tests := []struct {
+		name     string
+		method   string
+		loadErr  error
+		wantCode int
+	}{
+		{"Post", http.MethodPost, nil, http.StatusOK},
+		{"Get", http.MethodGet, nil, http.StatusMethodNotAllowed},
+		{"PostWithError", http.MethodPost, errors.New("load error"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loadErr = tt.loadErr
+			rec := httptest.NewRecorder()
+			reloadHandler(reloader, getLogger()).ServeHTTP(rec, httptest.NewRequest(tt.method, reloadEndpoint, nil))
+			if rec.Code != tt.wantCode {
+				t.Errorf("\nVariables do not match:\n%d\nwant:\n%d", rec.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestParseHistorySource(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    HistorySource
+		wantErr bool
+	}{
+		{"ValidSource", "cluster1=/data/gpbackup_history.db", HistorySource{"cluster1", "/data/gpbackup_history.db"}, false},
+		{"NoSeparator", "/data/gpbackup_history.db", HistorySource{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHistorySource(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("\nVariables do not match:\n%v\nwantErr:\n%v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateHistorySources(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources []HistorySource
+		wantErr bool
+	}{
+		{"ValidSources", []HistorySource{{"cluster1", "/data/1.db"}, {"cluster2", "/data/2.db"}}, false},
+		{"EmptyName", []HistorySource{{"", "/data/1.db"}}, true},
+		{"EmptyHistoryFile", []HistorySource{{"cluster1", ""}}, true},
+		{"DuplicateName", []HistorySource{{"cluster1", "/data/1.db"}, {"cluster1", "/data/2.db"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateHistorySources(tt.sources); (err != nil) != tt.wantErr {
+				t.Errorf("\nVariables do not match:\n%v\nwantErr:\n%v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateCatalogDB(t *testing.T) {
+	tests := []struct {
+		name      string
+		catalogDB string
+		sources   []HistorySource
+		wantErr   bool
+	}{
+		{"SingleSource", "postgres", []HistorySource{{"cluster1", "/data/1.db"}}, false},
+		{"SeveralSourcesWithoutCatalog", "", []HistorySource{{"cluster1", "/data/1.db"}, {"cluster2", "/data/2.db"}}, false},
+		{"SeveralSources", "postgres", []HistorySource{{"cluster1", "/data/1.db"}, {"cluster2", "/data/2.db"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateCatalogDB(tt.catalogDB, tt.sources); (err != nil) != tt.wantErr {
+				t.Errorf("\nVariables do not match:\n%v\nwantErr:\n%v", err, tt.wantErr)
+			}
+		})
+	}
+}
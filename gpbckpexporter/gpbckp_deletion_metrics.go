@@ -0,0 +1,134 @@
+package gpbckpexporter
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+// Deletion states of backups for metric labels.
+const (
+	deletionStateActive             = "active"
+	deletionStateDeleted            = "deleted"
+	deletionStateInProgress         = "in_progress"
+	deletionStatePluginDeleteFailed = "plugin_delete_failed"
+	deletionStateLocalDeleteFailed  = "local_delete_failed"
+)
+
+var deletionStates = []string{
+	deletionStateActive,
+	deletionStateDeleted,
+	deletionStateInProgress,
+	deletionStatePluginDeleteFailed,
+	deletionStateLocalDeleteFailed,
+}
+
+var (
+	gpbckpBackupDeletionStateBackupsMetric = newBackupDeletionStateBackupsMetric()
+	gpbckpBackupDeletionAgeSecondsMetric   = newBackupDeletionAgeSecondsMetric()
+)
+
+// Number of backups in each deletion state by database.
+type deletionStateMap map[string]map[string]int
+
+// Get deletion state of backup, the same states as for getDeletedStatusCode.
+func getDeletionState(dateDeleted string) string {
+	switch dateDeleted {
+	case "":
+		return deletionStateActive
+	case gpbckpconfig.DateDeletedInProgress:
+		return deletionStateInProgress
+	case gpbckpconfig.DateDeletedPluginFailed:
+		return deletionStatePluginDeleteFailed
+	case gpbckpconfig.DateDeletedLocalFailed:
+		return deletionStateLocalDeleteFailed
+	default:
+		return deletionStateDeleted
+	}
+}
+
+// Set backup deletion metrics:
+//   - gpbackup_backup_deletion_age_seconds
+//
+// Backup is counted in its deletion state.
+// Age is set only for backups in non-terminal deletion states,
+// it's calculated from the time, when exporter first saw backup in the current state.
+// Without tracker age isn't set.
+func getBackupDeletionMetrics(cluster, bckpType string, backupData gpbckpconfig.BackupConfig, tracker *deletionTracker, states deletionStateMap, currentUnixTime int64, setUpMetricValueFun setUpMetricValueFunType, logger *slog.Logger) {
+	state := getDeletionState(backupData.DateDeleted)
+	if _, ok := states[backupData.DatabaseName]; !ok {
+		states[backupData.DatabaseName] = make(map[string]int, len(deletionStates))
+	}
+	states[backupData.DatabaseName][state]++
+	if tracker == nil {
+		return
+	}
+	firstSeen, ok := tracker.firstSeen(cluster, backupData.Timestamp, backupData.DateDeleted)
+	if !ok {
+		return
+	}
+	setUpMetric(
+		gpbckpBackupDeletionAgeSecondsMetric,
+		"gpbackup_backup_deletion_age_seconds",
+		time.Unix(currentUnixTime, 0).Sub(firstSeen).Seconds(),
+		setUpMetricValueFun,
+		logger,
+		bckpType,
+		cluster,
+		backupData.DatabaseName,
+		state,
+		backupData.Timestamp,
+	)
+}
+
+// Set database deletion state metrics:
+//   - gpbackup_backup_deletion_state_backups
+//
+// Metric is set for all deletion states, including states without backups.
+func getDeletionStateMetrics(cluster string, states deletionStateMap, setUpMetricValueFun setUpMetricValueFunType, logger *slog.Logger) {
+	for db, counts := range states {
+		for _, state := range deletionStates {
+			setUpMetric(
+				gpbckpBackupDeletionStateBackupsMetric,
+				"gpbackup_backup_deletion_state_backups",
+				float64(counts[state]),
+				setUpMetricValueFun,
+				logger,
+				cluster,
+				db,
+				state,
+			)
+		}
+	}
+}
+
+func resetDeletionMetrics() {
+	gpbckpBackupDeletionStateBackupsMetric.Reset()
+	gpbckpBackupDeletionAgeSecondsMetric.Reset()
+}
+
+func newBackupDeletionStateBackupsMetric() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_backup_deletion_state_backups",
+		Help: "Number of backups in deletion state.",
+	},
+		[]string{
+			"cluster",
+			"database_name",
+			"state"})
+}
+
+func newBackupDeletionAgeSecondsMetric() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_backup_deletion_age_seconds",
+		Help: "Seconds since backup was first seen in non-terminal deletion state.",
+	},
+		[]string{
+			"backup_type",
+			"cluster",
+			"database_name",
+			"state",
+			"timestamp"})
+}
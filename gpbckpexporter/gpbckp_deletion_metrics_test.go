@@ -0,0 +1,98 @@
+package gpbckpexporter
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+func TestGetBackupDeletionMetrics(t *testing.T) {
+	templateMetrics := `# HELP gpbackup_backup_deletion_age_seconds Seconds since backup was first seen in non-terminal deletion state.
+# TYPE gpbackup_backup_deletion_age_seconds gauge
+gpbackup_backup_deletion_age_seconds{backup_type="full",cluster="",database_name="test",state="in_progress",timestamp="20230118152654"} 3600
+gpbackup_backup_deletion_age_seconds{backup_type="full",cluster="",database_name="test",state="plugin_delete_failed",timestamp="20230118162654"} 0
+# HELP gpbackup_backup_deletion_state_backups Number of backups in deletion state.
+# TYPE gpbackup_backup_deletion_state_backups gauge
+gpbackup_backup_deletion_state_backups{cluster="",database_name="test",state="active"} 1
+gpbackup_backup_deletion_state_backups{cluster="",database_name="test",state="deleted"} 1
+gpbackup_backup_deletion_state_backups{cluster="",database_name="test",state="in_progress"} 1
+gpbackup_backup_deletion_state_backups{cluster="",database_name="test",state="local_delete_failed"} 0
+gpbackup_backup_deletion_state_backups{cluster="",database_name="test",state="plugin_delete_failed"} 1
+`
+	backups := make([]gpbckpconfig.BackupConfig, 0, 4)
+	for _, value := range []struct{ timestamp, dateDeleted string }{
+		{"20230118152654", gpbckpconfig.DateDeletedInProgress},
+		{"20230118162654", gpbckpconfig.DateDeletedPluginFailed},
+		{"20230118172654", ""},
+		{"20230118182654", "20230119150000"},
+	} {
+		backupData := templateBackupConfig()
+		backupData.Timestamp = value.timestamp
+		backupData.DateDeleted = value.dateDeleted
+		backups = append(backups, backupData)
+	}
+	tracker := newDeletionTracker("")
+	// Backup was seen in the same state in previous collection.
+	tracker.update("", pendingDeletionMap{"20230118152654": gpbckpconfig.DateDeletedInProgress}, time.Unix(templateUnixTime()-3600, 0))
+	tracker.update("", pendingDeletionMap{
+		"20230118152654": gpbckpconfig.DateDeletedInProgress,
+		"20230118162654": gpbckpconfig.DateDeletedPluginFailed,
+	}, time.Unix(templateUnixTime(), 0))
+	resetDeletionMetrics()
+	states := make(deletionStateMap)
+	for _, backupData := range backups {
+		getBackupDeletionMetrics("", "full", backupData, tracker, states, templateUnixTime(), setUpMetricValue, getLogger())
+	}
+	getDeletionStateMetrics("", states, setUpMetricValue, getLogger())
+	got := gatherMetricsText(t, gpbckpBackupDeletionAgeSecondsMetric, gpbckpBackupDeletionStateBackupsMetric)
+	if got != templateMetrics {
+		t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", got, templateMetrics)
+	}
+}
+
+func TestCollectBackupInfoDeletionAgeWithFilter(t *testing.T) {
+	backupData := templateBackupConfig()
+	backupData.DateDeleted = gpbckpconfig.DateDeletedPluginFailed
+	historyFile := fakeHistoryFileBackups(t, backupData)
+	defer os.Remove(historyFile)
+	defer delete(historyCaches, historyFile)
+	firstTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	tracker := newDeletionTracker("")
+	tracker.update("", pendingDeletionMap{backupData.Timestamp: backupData.DateDeleted}, firstTime)
+	// Backup is filtered out by backup type, but it's still tracked.
+	for _, backupType := range []string{gpbckpconfig.BackupTypeIncremental, ""} {
+		resetMetrics()
+		if _, err := collectBackupInfo(context.Background(), "", historyFile, CollectConfig{BackupType: backupType}, tracker, setUpMetricValue, getLogger()); err != nil {
+			t.Fatalf("\nGet error during collect backup info:\n%v", err)
+		}
+		if firstSeen, ok := tracker.firstSeen("", backupData.Timestamp, backupData.DateDeleted); !ok || !firstSeen.Equal(firstTime) {
+			t.Errorf("\nVariables do not match:\n%v, %v\nwant:\n%v, %v", firstSeen, ok, firstTime, true)
+		}
+	}
+	age := &dto.Metric{}
+	if err := gpbckpBackupDeletionAgeSecondsMetric.WithLabelValues("full", "", "test", deletionStatePluginDeleteFailed, backupData.Timestamp).Write(age); err != nil {
+		t.Fatalf("\nGet error during write metric:\n%v", err)
+	}
+	if age.GetGauge().GetValue() < time.Hour.Seconds() {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n>= %v", age.GetGauge().GetValue(), time.Hour.Seconds())
+	}
+}
+
+func TestCollectBackupInfoDeletionWithoutTracker(t *testing.T) {
+	backupData := templateBackupConfig()
+	backupData.DateDeleted = gpbckpconfig.DateDeletedInProgress
+	historyFile := fakeHistoryFileBackups(t, backupData)
+	defer os.Remove(historyFile)
+	defer delete(historyCaches, historyFile)
+	resetMetrics()
+	if _, err := collectBackupInfo(context.Background(), "", historyFile, CollectConfig{}, nil, setUpMetricValue, getLogger()); err != nil {
+		t.Fatalf("\nGet error during collect backup info:\n%v", err)
+	}
+	if got := gatherMetricsText(t, gpbckpBackupDeletionAgeSecondsMetric); got != "" {
+		t.Errorf("\nVariables do not match:\n%s\nwant:\nempty", got)
+	}
+}
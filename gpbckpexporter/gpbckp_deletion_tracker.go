@@ -0,0 +1,170 @@
+package gpbckpexporter
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+// History file contains only the current deletion state of backup,
+// so exporter remembers when it first saw backup in non-terminal deletion state.
+// State is saved to file, so deletion age isn't reset after exporter restart.
+
+// Version of deletion state file format.
+const deletionStateVersion = 1
+
+// Deletion state of backup and time when exporter first saw backup in this state.
+type deletionEntry struct {
+	State     string    `json:"state"`
+	FirstSeen time.Time `json:"first_seen"`
+}
+
+// Content of deletion state file.
+type deletionStateFile struct {
+	Version int `json:"version"`
+	// Deletion states by cluster and backup timestamp.
+	Clusters map[string]map[string]deletionEntry `json:"clusters"`
+}
+
+// Tracker of backups in non-terminal deletion states.
+// Must be used with collectMutex held.
+// Probes don't use tracker, so probe requests don't change saved states.
+type deletionTracker struct {
+	// Path to state file, empty - state isn't saved.
+	file string
+	// Deletion states by cluster and backup timestamp.
+	clusters map[string]map[string]deletionEntry
+	// State is changed since the last save.
+	changed bool
+}
+
+var gpbckpDeletionTracker = newDeletionTracker("")
+
+func newDeletionTracker(file string) *deletionTracker {
+	return &deletionTracker{
+		file:     file,
+		clusters: make(map[string]map[string]deletionEntry),
+	}
+}
+
+// SetDeletionStateFile sets file for saving deletion states of backups between restarts
+// and loads saved states.
+// If file can't be loaded, tracking starts from scratch and error is returned.
+func SetDeletionStateFile(file string) error {
+	tracker := newDeletionTracker(file)
+	err := tracker.load()
+	gpbckpDeletionTracker = tracker
+	return err
+}
+
+// Non-terminal deletion states of backups by timestamp.
+type pendingDeletionMap map[string]string
+
+// Check that deletion of backup isn't finished:
+// deletion is in progress or the last delete attempt failed.
+func isDeletionPending(dateDeleted string) bool {
+	switch dateDeleted {
+	case gpbckpconfig.DateDeletedInProgress, gpbckpconfig.DateDeletedPluginFailed, gpbckpconfig.DateDeletedLocalFailed:
+		return true
+	}
+	return false
+}
+
+// Update deletion states of cluster by backups in non-terminal deletion states.
+// For backups in the same state the first time is kept, for new states it's set to now.
+// Backups, which aren't in non-terminal deletion states anymore or are removed
+// from history file, aren't tracked.
+func (t *deletionTracker) update(cluster string, pending pendingDeletionMap, now time.Time) {
+	backups := t.clusters[cluster]
+	for timestamp := range backups {
+		if _, ok := pending[timestamp]; !ok {
+			delete(backups, timestamp)
+			t.changed = true
+		}
+	}
+	for timestamp, dateDeleted := range pending {
+		if entry, ok := backups[timestamp]; ok && entry.State == dateDeleted {
+			continue
+		}
+		if backups == nil {
+			backups = make(map[string]deletionEntry)
+			t.clusters[cluster] = backups
+		}
+		backups[timestamp] = deletionEntry{State: dateDeleted, FirstSeen: now}
+		t.changed = true
+	}
+	if len(backups) == 0 {
+		delete(t.clusters, cluster)
+	}
+}
+
+// Get time when exporter first saw backup in the current non-terminal deletion state.
+// Backups in other states aren't tracked.
+func (t *deletionTracker) firstSeen(cluster, timestamp, dateDeleted string) (time.Time, bool) {
+	entry, ok := t.clusters[cluster][timestamp]
+	if !ok || entry.State != dateDeleted {
+		return time.Time{}, false
+	}
+	return entry.FirstSeen, true
+}
+
+// Load deletion states from file.
+// Missing file isn't an error, it's created on the first save.
+func (t *deletionTracker) load() error {
+	if t.file == "" {
+		return nil
+	}
+	data, err := os.ReadFile(t.file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var state deletionStateFile
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if state.Version != deletionStateVersion {
+		return errors.New("unsupported deletion state file version")
+	}
+	for cluster, backups := range state.Clusters {
+		if len(backups) != 0 {
+			t.clusters[cluster] = backups
+		}
+	}
+	return nil
+}
+
+// Save deletion states to file, if they are changed.
+// File is replaced atomically, so it isn't corrupted, if exporter is stopped during saving.
+func (t *deletionTracker) save() error {
+	if t.file == "" || !t.changed {
+		return nil
+	}
+	data, err := json.Marshal(deletionStateFile{Version: deletionStateVersion, Clusters: t.clusters})
+	if err != nil {
+		return err
+	}
+	tempFile, err := os.CreateTemp(filepath.Dir(t.file), filepath.Base(t.file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tempFile.Name(), t.file); err != nil {
+		return err
+	}
+	t.changed = false
+	return nil
+}
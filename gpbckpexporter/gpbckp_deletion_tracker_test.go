@@ -0,0 +1,113 @@
+package gpbckpexporter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+func TestDeletionTrackerUpdate(t *testing.T) {
+	firstTime := returnTimeTime("20230118150000")
+	secondTime := returnTimeTime("20230118160000")
+	tests := []struct {
+		name      string
+		pending   pendingDeletionMap
+		now       time.Time
+		firstSeen time.Time
+		ok        bool
+	}{
+		{"NewState", pendingDeletionMap{"20230118152654": gpbckpconfig.DateDeletedInProgress}, firstTime, firstTime, true},
+		{"SameState", pendingDeletionMap{"20230118152654": gpbckpconfig.DateDeletedInProgress}, secondTime, firstTime, true},
+		{"ChangedState", pendingDeletionMap{"20230118152654": gpbckpconfig.DateDeletedPluginFailed}, secondTime, secondTime, true},
+		{"Deleted", pendingDeletionMap{}, secondTime, time.Time{}, false},
+	}
+	tracker := newDeletionTracker("")
+	// Steps are applied to the same tracker one by one.
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker.update("", tt.pending, tt.now)
+			firstSeen, ok := tracker.firstSeen("", "20230118152654", tt.pending["20230118152654"])
+			if ok != tt.ok || !firstSeen.Equal(tt.firstSeen) {
+				t.Errorf("\nVariables do not match:\n%v, %v\nwant:\n%v, %v", firstSeen, ok, tt.firstSeen, tt.ok)
+			}
+		})
+	}
+	if len(tracker.clusters) != 0 {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\nempty", tracker.clusters)
+	}
+}
+
+func TestDeletionTrackerUpdateClusters(t *testing.T) {
+	now := returnTimeTime("20230118150000")
+	tracker := newDeletionTracker("")
+	tracker.update("cluster1", pendingDeletionMap{
+		"20230118152654": gpbckpconfig.DateDeletedInProgress,
+		"20230118162654": gpbckpconfig.DateDeletedLocalFailed,
+	}, now)
+	tracker.update("cluster2", pendingDeletionMap{"20230118152654": gpbckpconfig.DateDeletedInProgress}, now)
+	// Backup isn't in non-terminal deletion state in the next collection.
+	tracker.update("cluster1", pendingDeletionMap{"20230118162654": gpbckpconfig.DateDeletedLocalFailed}, now)
+	want := map[string]map[string]deletionEntry{
+		"cluster1": {"20230118162654": {gpbckpconfig.DateDeletedLocalFailed, now}},
+		"cluster2": {"20230118152654": {gpbckpconfig.DateDeletedInProgress, now}},
+	}
+	if !reflect.DeepEqual(tracker.clusters, want) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", tracker.clusters, want)
+	}
+}
+
+func TestDeletionTrackerSaveLoad(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "deletion_state.json")
+	now := time.Unix(templateUnixTime(), 0).UTC()
+	tracker := newDeletionTracker(stateFile)
+	if err := tracker.load(); err != nil {
+		t.Fatalf("\nGet error during load missing state file:\n%v", err)
+	}
+	tracker.update("", pendingDeletionMap{"20230118152654": gpbckpconfig.DateDeletedInProgress}, now)
+	if err := tracker.save(); err != nil {
+		t.Fatalf("\nGet error during save state file:\n%v", err)
+	}
+	loaded := newDeletionTracker(stateFile)
+	if err := loaded.load(); err != nil {
+		t.Fatalf("\nGet error during load state file:\n%v", err)
+	}
+	if !reflect.DeepEqual(loaded.clusters, tracker.clusters) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", loaded.clusters, tracker.clusters)
+	}
+	entries, err := os.ReadDir(filepath.Dir(stateFile))
+	if err != nil {
+		t.Fatalf("\nGet error during read dir:\n%v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("\nVariables do not match:\n%d\nwant:\n%d", len(entries), 1)
+	}
+}
+
+func TestDeletionTrackerLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"CorruptFile", "{"},
+		{"UnsupportedVersion", `{"version":2,"clusters":{}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stateFile := filepath.Join(t.TempDir(), "deletion_state.json")
+			if err := os.WriteFile(stateFile, []byte(tt.data), 0600); err != nil {
+				t.Fatalf("Failed to create state file: %v", err)
+			}
+			if err := SetDeletionStateFile(stateFile); err == nil {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\nerror", err)
+			}
+			if len(gpbckpDeletionTracker.clusters) != 0 {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\nempty", gpbckpDeletionTracker.clusters)
+			}
+			SetDeletionStateFile("")
+		})
+	}
+}
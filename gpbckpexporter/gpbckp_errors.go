@@ -0,0 +1,123 @@
+package gpbckpexporter
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Kinds of errors during getting data from history database.
+var (
+	errWrongExtension   = errors.New("wrong extension")
+	errFileNotFound     = errors.New("file not found")
+	errPermissionDenied = errors.New("permission denied")
+	errDatabaseLocked   = errors.New("database locked")
+	errFileChanged      = errors.New("file changed")
+	errCorruptFile      = errors.New("corrupt file")
+	errUnexpectedSchema = errors.New("unexpected schema")
+	errRowDecode        = errors.New("row decode failure")
+)
+
+// Values of reason label for error kinds.
+const errorReasonUnknown = "unknown"
+
+var errorReasons = []struct {
+	kind   error
+	reason string
+}{
+	{errWrongExtension, "wrong_extension"},
+	{errFileNotFound, "file_not_found"},
+	{errPermissionDenied, "permission_denied"},
+	{errDatabaseLocked, "database_locked"},
+	{errFileChanged, "file_changed"},
+	{errCorruptFile, "corrupt_file"},
+	{errUnexpectedSchema, "unexpected_schema"},
+	{errRowDecode, "row_decode"},
+}
+
+// Error during getting data from history database with its kind.
+// Kind can be checked via errors.Is, original error is available via errors.Unwrap.
+type historyError struct {
+	kind error
+	err  error
+}
+
+func (e *historyError) Error() string {
+	return e.err.Error()
+}
+
+func (e *historyError) Unwrap() error {
+	return e.err
+}
+
+func (e *historyError) Is(target error) bool {
+	return e.kind != nil && e.kind == target
+}
+
+// Get error with kind detected by original error.
+// Errors, which already have kind, are returned as is.
+func newHistoryError(err error) error {
+	var hErr *historyError
+	if err == nil || errors.As(err, &hErr) {
+		return err
+	}
+	return &historyError{kind: getErrorKind(err), err: err}
+}
+
+// Get error with row decode kind.
+func newRowDecodeError(err error) error {
+	return &historyError{kind: errRowDecode, err: err}
+}
+
+// Detect error kind by original error.
+// For unknown errors nil is returned.
+func getErrorKind(err error) error {
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return errFileNotFound
+	case errors.Is(err, os.ErrPermission):
+		return errPermissionDenied
+	}
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return nil
+	}
+	switch sqliteErr.Code {
+	case sqlite3.ErrBusy, sqlite3.ErrLocked:
+		return errDatabaseLocked
+	case sqlite3.ErrCorrupt, sqlite3.ErrNotADB:
+		return errCorruptFile
+	case sqlite3.ErrPerm:
+		return errPermissionDenied
+	case sqlite3.ErrCantOpen:
+		if errors.Is(sqliteErr.SystemErrno, os.ErrPermission) {
+			return errPermissionDenied
+		}
+	case sqlite3.ErrError:
+		// History database doesn't contain expected tables or columns,
+		// e.g. it's created by unsupported gpbackup version.
+		msg := sqliteErr.Error()
+		if strings.Contains(msg, "no such table") || strings.Contains(msg, "no such column") {
+			return errUnexpectedSchema
+		}
+	}
+	return nil
+}
+
+// Get value of reason label for error.
+func getErrorReason(err error) string {
+	for _, r := range errorReasons {
+		if errors.Is(err, r.kind) {
+			return r.reason
+		}
+	}
+	return errorReasonUnknown
+}
+
+// Check that error is caused by concurrent writing to history file.
+// Such errors are temporary, so reading is retried.
+func isBusyError(err error) bool {
+	return errors.Is(err, errDatabaseLocked) || errors.Is(err, errFileChanged)
+}
@@ -0,0 +1,99 @@
+package gpbckpexporter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+func TestGetErrorReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"WrongExtension", &historyError{errWrongExtension, errors.New("file has an extension other than db (sqlite)")}, "wrong_extension"},
+		{"FileNotFound", newHistoryError(&fs.PathError{Op: "open", Path: "test.db", Err: fs.ErrNotExist}), "file_not_found"},
+		{"PermissionDenied", newHistoryError(&fs.PathError{Op: "open", Path: "test.db", Err: fs.ErrPermission}), "permission_denied"},
+		{"DatabaseBusy", newHistoryError(sqlite3.Error{Code: sqlite3.ErrBusy}), "database_locked"},
+		{"DatabaseLocked", newHistoryError(sqlite3.Error{Code: sqlite3.ErrLocked}), "database_locked"},
+		{"CantOpenPermission", newHistoryError(sqlite3.Error{Code: sqlite3.ErrCantOpen, SystemErrno: syscall.EACCES}), "permission_denied"},
+		{"NotADatabase", newHistoryError(sqlite3.Error{Code: sqlite3.ErrNotADB}), "corrupt_file"},
+		{"RowDecode", newRowDecodeError(errors.New("converting NULL to string is unsupported")), "row_decode"},
+		{"WrappedError", fmt.Errorf("collect: %w", newRowDecodeError(errors.New("scan error"))), "row_decode"},
+		{"UnknownError", newHistoryError(errors.New("unknown error")), "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getErrorReason(tt.err); got != tt.want {
+				t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBackupDataErrors(t *testing.T) {
+	dir := t.TempDir()
+	corruptFile := filepath.Join(dir, "corrupt.db")
+	if err := os.WriteFile(corruptFile, []byte("this is not a sqlite database, but a plain text file"), 0600); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	corruptYAMLFile := filepath.Join(dir, "corrupt.yaml")
+	if err := os.WriteFile(corruptYAMLFile, []byte("backupconfigs: [unclosed"), 0600); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	schemaFile := filepath.Join(dir, "schema.db")
+	fakeHistoryDB(t, schemaFile, "CREATE TABLE other (id INT);")
+	decodeFile := filepath.Join(dir, "decode.db")
+	decodeHistoryFile := fakeHistoryFileBackups(t, templateBackupConfig())
+	defer os.Remove(decodeHistoryFile)
+	if err := os.Rename(decodeHistoryFile, decodeFile); err != nil {
+		t.Fatalf("Failed to rename test file: %v", err)
+	}
+	fakeHistoryDB(t, decodeFile, "UPDATE backups SET compressed = NULL;")
+	tests := []struct {
+		name        string
+		historyFile string
+		want        error
+	}{
+		{"WrongExtension", filepath.Join(dir, "history.txt"), errWrongExtension},
+		{"CorruptYAMLFile", corruptYAMLFile, errCorruptFile},
+		{"FileNotFound", filepath.Join(dir, "missing.db"), errFileNotFound},
+		{"CorruptFile", corruptFile, errCorruptFile},
+		{"UnexpectedSchema", schemaFile, errUnexpectedSchema},
+		{"RowDecode", decodeFile, errRowDecode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer delete(historyCaches, tt.historyFile)
+			_, _, _, err := parseBackupData(context.Background(), tt.historyFile, backupFilter{}, getLogger())
+			if !errors.Is(err, tt.want) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", err, tt.want)
+			}
+		})
+	}
+	// Missing history file must not be created.
+	if _, err := os.Stat(filepath.Join(dir, "missing.db")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", err, os.ErrNotExist)
+	}
+}
+
+// Execute query in sqlite database, database is created if it doesn't exist.
+func fakeHistoryDB(tb testing.TB, file, query string) {
+	hDB, err := sql.Open("sqlite3", file)
+	if err != nil {
+		tb.Fatalf("Failed to open test database: %v", err)
+	}
+	defer hDB.Close()
+	if _, err := hDB.Exec(query); err != nil {
+		tb.Fatalf("Failed to execute query: %v", err)
+	}
+}
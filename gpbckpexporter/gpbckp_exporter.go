@@ -1,9 +1,13 @@
 package gpbckpexporter
 
 import (
+	"context"
+	"errors"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -11,9 +15,15 @@ import (
 	"github.com/woblerr/gpbackman/gpbckpconfig"
 )
 
+// Maximum time to wait for active HTTP connections during shutdown.
+const shutdownTimeout = 10 * time.Second
+
 var (
 	webFlagsConfig web.FlagConfig
 	webEndpoint    string
+	// Closed, when HTTP server starts accepting connections.
+	webListening     = make(chan struct{})
+	webListeningOnce sync.Once
 )
 
 // SetPromPortAndPath sets HTTP endpoint parameters
@@ -27,13 +37,32 @@ func SetPromPortAndPath(flagsConfig web.FlagConfig, endpoint string) {
 	webEndpoint = endpoint
 }
 
-// StartPromEndpoint run HTTP endpoint
-func StartPromEndpoint(version string, logger *slog.Logger) {
+// StartPromEndpoint run HTTP endpoint.
+// The server is gracefully shut down when context is canceled.
+// The returned channel is closed after server shutdown.
+func StartPromEndpoint(ctx context.Context, version string, logger *slog.Logger) <-chan struct{} {
+	server := &http.Server{
+		ReadHeaderTimeout: 5 * time.Second,
+		// It's called, when server starts accepting connections on listener.
+		BaseContext: func(net.Listener) context.Context {
+			webListeningOnce.Do(func() { close(webListening) })
+			return context.Background()
+		},
+	}
+	done := make(chan struct{})
 	go func(logger *slog.Logger) {
 		if webEndpoint == "" {
 			logger.Error("Metric endpoint is empty", "endpoint", webEndpoint)
 		}
 		http.Handle(webEndpoint, promhttp.Handler())
+		http.Handle(healthyEndpoint, healthyHandler(gpbckpHealth, logger))
+		http.Handle(readyEndpoint, readyHandler(gpbckpHealth, logger))
+		if configReloader != nil {
+			http.Handle(reloadEndpoint, reloadHandler(configReloader, logger))
+		}
+		if len(probeTargets) != 0 {
+			http.Handle(probeEndpoint, probeHandler(probeTargets, logger))
+		}
 		if webEndpoint != "/" {
 			landingConfig := web.LandingConfig{
 				Name:        "gpbackup exporter",
@@ -46,6 +75,14 @@ func StartPromEndpoint(version string, logger *slog.Logger) {
 						Address: webEndpoint,
 						Text:    "Metrics",
 					},
+					{
+						Address: healthyEndpoint,
+						Text:    "Health",
+					},
+					{
+						Address: readyEndpoint,
+						Text:    "Readiness",
+					},
 				},
 			}
 			landingPage, err := web.NewLandingPage(landingConfig)
@@ -55,65 +92,180 @@ func StartPromEndpoint(version string, logger *slog.Logger) {
 			}
 			http.Handle("/", landingPage)
 		}
-		server := &http.Server{
-			ReadHeaderTimeout: 5 * time.Second,
-		}
-		if err := web.ListenAndServe(server, &webFlagsConfig, logger); err != nil {
+		if err := web.ListenAndServe(server, &webFlagsConfig, logger); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			logger.Error("Run web endpoint failed", "err", err)
 			os.Exit(1)
 		}
 	}(logger)
+	go func(logger *slog.Logger) {
+		defer close(done)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Error("Shutdown web endpoint failed", "err", err)
+		}
+	}(logger)
+	return done
 }
 
-// GetGPBackupInfo get and parse gpbackup history file
-func GetGPBackupInfo(historyFile, backupType string, collectDeleted, collectFailed bool, dbInclude, dbExclude []string, collectDepth int, logger *slog.Logger) {
-	var parseHData gpbckpconfig.History
-	// The flag indicates whether it was possible to get data from the gpbackup history.
-	// By default, it's set to true.
-	getDataSuccessStatus := true
+// GetGPBackupInfo get and parse gpbackup history file.
+// Collected metrics are published for collector at the end of the function.
+// If context is canceled during collection, previous metrics are kept.
+// All metrics are labeled with cluster name of history source.
+func GetGPBackupInfo(ctx context.Context, cluster, historyFile string, config CollectConfig, logger *slog.Logger) {
+	if cluster != "" {
+		logger = logger.With("cluster", cluster)
+	}
+	start := time.Now()
+	// History file is read without collectMutex held,
+	// so retries for locked history database don't block other sources and probes.
+	info := readBackupInfo(ctx, historyFile, config, logger)
+	collectMutex.Lock()
+	defer collectMutex.Unlock()
+	// Reset metrics.
+	resetMetrics()
+	stats, err := setUpBackupInfo(ctx, cluster, config, info, gpbckpDeletionTracker, setUpMetricValue, logger)
+	if ctx.Err() != nil {
+		return
+	}
+	snapshot := snapshotMetrics()
+	// When history database is still busy after all retries,
+	// metrics from the previous collection are served and marked as stale.
+	stale := false
+	if isBusyError(err) {
+		if prevSnapshot, ok := gpbckpCollector.snapshot(cluster); ok {
+			logger.Warn("History db is busy, previous metrics are served")
+			snapshot = prevSnapshot
+			stale = true
+		}
+	}
+	// Publish collected metrics.
+	if !stale {
+		gpbckpCollector.update(cluster, snapshot)
+	}
+	duration := time.Since(start)
+	setCollectionMetrics(cluster, duration, stats, len(snapshot), stale, err)
+	cycleDone := gpbckpHealth.record(cluster, err)
+	notifyCollection(cluster, len(snapshot), duration, err, cycleDone, logger)
+}
+
+// Data read from history file for one collection.
+type backupInfo struct {
+	hData   gpbckpconfig.History
+	dbNames []string
+	stats   historyStats
+	err     error
+	// Databases from catalog, nil - catalog isn't set or isn't available.
+	catalogDBs []string
 	// To calculate the time elapsed since the last completed backup for specific database.
 	// For all databases values are calculated relative to one value.
+	currentTime time.Time
+}
+
+// Get and parse gpbackup history file and set up metrics via setUpMetricValueFun.
+// Deletion states of backups are tracked via tracker, if it's set.
+// Returns statistics of reading history file
+// and error, if data can't be got from history file or context is canceled.
+// Must be called with collectMutex held, if global metrics or tracker are used.
+func collectBackupInfo(ctx context.Context, cluster, historyFile string, config CollectConfig, tracker *deletionTracker, setUpMetricValueFun setUpMetricValueFunType, logger *slog.Logger) (historyStats, error) {
+	info := readBackupInfo(ctx, historyFile, config, logger)
+	return setUpBackupInfo(ctx, cluster, config, info, tracker, setUpMetricValueFun, logger)
+}
+
+// Get and parse gpbackup history file and get databases from catalog.
+// It doesn't use global metrics and tracker, so it's called without collectMutex held.
+func readBackupInfo(ctx context.Context, historyFile string, config CollectConfig, logger *slog.Logger) backupInfo {
 	currentTime := time.Now()
+	// Filters for deleted and failed backups, databases, backup type and collection depth
+	// are applied via sql queries, so only relevant backups are read from history database.
+	// The same filters are applied in setUpBackupInfo for the result data.
+	// It doesn't change the result, but keeps the logic independent of the way data is obtained.
+	filter := backupFilter{
+		collectDeleted: config.CollectDeleted,
+		collectFailed:  config.CollectFailed,
+		backupType:     config.BackupType,
+		dbInclude:      config.DBInclude,
+		dbExclude:      config.DBExclude,
+	}
+	if config.CollectDepth > 0 {
+		filter.timestampAfter = currentTime.AddDate(0, 0, -config.CollectDepth).Format(gpbckpconfig.Layout)
+	}
+	info := backupInfo{currentTime: currentTime}
+	info.hData, info.dbNames, info.stats, info.err = parseBackupData(ctx, historyFile, filter, logger)
+	// Catalog is used only when history file is read successfully.
+	if info.err == nil && ctx.Err() == nil {
+		info.catalogDBs = loadCatalogDatabases(ctx, config, logger)
+	}
+	return info
+}
+
+// Set up metrics via setUpMetricValueFun for data read from history file.
+// Must be called with collectMutex held, if global metrics or tracker are used.
+func setUpBackupInfo(ctx context.Context, cluster string, config CollectConfig, info backupInfo, tracker *deletionTracker, setUpMetricValueFun setUpMetricValueFunType, logger *slog.Logger) (historyStats, error) {
+	parseHData, dbNames, stats, err := info.hData, info.dbNames, info.stats, info.err
+	// The flag indicates whether it was possible to get data from the gpbackup history.
+	// By default, it's set to true.
+	getDataSuccessStatus := true
+	currentTime := info.currentTime
 	currentUnixTime := currentTime.Unix()
 	// Calculate metrics collection depth.
 	// For backups with timestamp older than this - metrics doesn't collect.
-	collectDepthTime := currentTime.AddDate(0, 0, -collectDepth)
-	// The old logic has been left unchanged.
-	// Although yaml format support has been removed, the code is working.
-	// Below is the original comment (for the case of working with both yaml and sqlite formats).
-	//
-	// Because there are two different format of history database,
-	// it's better to use different methods to get data from yaml and sqlite format.
-	// For yaml we get all data from history file, parse and than filter data.
-	// For sqlite we can filter data via sql queries. It's not necessary to get all data.
-	// But in current code we reuse code for yaml for sqlite.
-	// It's should be refactored in future. For example, when exporter will be switched
-	// only on sqlite format for history database.
-	// However, even now, we can reduce backup number using filters for deleted and failed backups.
-	parseHData, err := parseBackupData(historyFile, collectDeleted, collectFailed, logger)
+	collectDepthTime := currentTime.AddDate(0, 0, -config.CollectDepth)
+	if ctx.Err() != nil {
+		logger.Warn("Collection canceled", "err", ctx.Err())
+		return stats, ctx.Err()
+	}
 	if err != nil {
 		logger.Error("Get data failed", "err", err)
 		getDataSuccessStatus = false
 	}
-	// Reset metrics.
-	resetMetrics()
-	if len(parseHData.BackupConfigs) != 0 {
-		// Like lastbackups["testDB"]["full"] = time
-		lastBackups := make(lastBackupMap)
-		dbStatus := make(dbStatusMap)
+	// Catalog databases are added to expected databases below.
+	dbExpected := config.DBExpected
+	// Deletion states of backups are tracked between collections.
+	// States of all backups are updated regardless of filters.
+	// If history file isn't read, tracked states are kept.
+	if tracker != nil && err == nil {
+		tracker.update(cluster, stats.pendingDeletions, currentTime)
+		if err := tracker.save(); err != nil {
+			logger.Error("Save deletion state file failed", "err", err)
+		}
+	}
+	// Like lastbackups["testDB"]["full"] = time
+	lastBackups := make(lastBackupMap)
+	dbStatus := make(dbStatusMap)
+	if len(dbNames) != 0 {
+		unrestorable := make(unrestorableMap)
+		dbDeletionStates := make(deletionStateMap)
+		// Set up metrics for single backup, which passes all filters.
+		setUpBackupMetrics := func(bckpType string, backupData gpbckpconfig.BackupConfig) {
+			getBackupMetrics(cluster, backupData, setUpMetricValueFun, logger)
+			getBackupChainMetrics(cluster, bckpType, backupData, stats.dependents, currentUnixTime, setUpMetricValueFun, logger)
+			getBackupRestorableMetrics(cluster, bckpType, backupData, stats.planStates, unrestorable, setUpMetricValueFun, logger)
+			getBackupInProgressMetrics(cluster, bckpType, backupData, config.InProgressThreshold, currentUnixTime, setUpMetricValueFun, logger)
+			getBackupDeletionMetrics(cluster, bckpType, backupData, tracker, dbDeletionStates, currentUnixTime, setUpMetricValueFun, logger)
+		}
+		// Exporter status is set for all databases with backups in history database,
+		// even if all their backups are filtered out by backup type or collection depth.
+		// Databases specified in include and exclude lists are processed below.
+		for _, db := range dbNames {
+			if !dbInList(db, config.DBExclude) {
+				dbStatus[db] = getDataSuccessStatus
+			}
+		}
 		for i := 0; i < len(parseHData.BackupConfigs); i++ {
 			db := parseHData.BackupConfigs[i].DatabaseName
 			// If the same database is specified in include and exclude list,
 			// then metrics for this database will not be collected.
-			if !dbInList(db, dbExclude) {
-				if listEmpty(dbInclude) || dbInList(db, dbInclude) {
+			if !dbInList(db, config.DBExclude) {
+				if listEmpty(config.DBInclude) || dbInList(db, config.DBInclude) {
 					dbStatus[db] = getDataSuccessStatus
 					bckpType, err := parseHData.BackupConfigs[i].GetBackupType()
 					if err != nil {
 						logger.Error("Parse backup type value failed", "err", err)
 					}
 					// Check backup type and compare with backup type filter.
-					if backupType == "" || backupType == bckpType {
+					if config.BackupType == "" || config.BackupType == bckpType {
 						// History file contains backup timestamp and endtime with timezone information.
 						// See https://github.com/greenplum-db/gpbackup/blob/722899aada32ec118eb311255ac521b691bb4360/backup/backup.go#L431-L432
 						// It is necessary to take this into account when calculating time intervals.
@@ -123,32 +275,24 @@ func GetGPBackupInfo(historyFile, backupType string, collectDeleted, collectFail
 						if err != nil {
 							logger.Error("Parse backup timestamp value failed", "err", err)
 						}
-						bckpStopTime, err := time.ParseInLocation(gpbckpconfig.Layout, parseHData.BackupConfigs[i].EndTime, time.Local)
-						if err != nil {
-							logger.Error("Parse backup end time value failed", "err", err)
-						}
-						// Only if set correct value for collectDepth.
-						if collectDepth > 0 {
-							// The old logic has been left unchanged.
-							// Although yaml format support has been removed, the code is working.
-							// Below is the original comment (for the case of working with both yaml and sqlite formats).
-							//
-							// gpbackup_history.yml file is sorted by timestamp values.
-							// The data of the most recent backup is always located at the beginning of the file.
-							// When Unmarshal, we get a sorted list.
-							// So as soon as we get the first value that is older than collectDepthTime,
-							// the cycle can be braked.
-							// If this behavior ever changes, then this code needs to be refactored.
-							// See https://github.com/greenplum-db/gpbackup/blame/64c06479043d5a41ce4512ba0549483b71824c2a/history/history.go#L103
-							// For results from gpbackup_history.db file a similar sort is performed to reuse this code.
-							if collectDepthTime.Before(bckpStartTime) {
-								getBackupMetrics(parseHData.BackupConfigs[i], setUpMetricValue, logger)
-							} else {
-								break
+						// End time isn't set, while backup is in progress.
+						var bckpStopTime time.Time
+						if !parseHData.BackupConfigs[i].IsInProgress() {
+							bckpStopTime, err = time.ParseInLocation(gpbckpconfig.Layout, parseHData.BackupConfigs[i].EndTime, time.Local)
+							if err != nil {
+								logger.Error("Parse backup end time value failed", "err", err)
 							}
-						} else {
-							getBackupMetrics(parseHData.BackupConfigs[i], setUpMetricValue, logger)
 						}
+						// Only if set correct value for collection depth.
+						// Backups older than collectDepthTime are already filtered out via sql query.
+						// Backups are sorted by timestamp in descending order (see loadBackupConfigsDB).
+						// So as soon as we get the first value that is older than collectDepthTime,
+						// the cycle can be braked.
+						// It's possible only for values, which can't be correctly compared in sql query.
+						if config.CollectDepth > 0 && !collectDepthTime.Before(bckpStartTime) {
+							break
+						}
+						setUpBackupMetrics(bckpType, parseHData.BackupConfigs[i])
 						if parseHData.BackupConfigs[i].Status == "Success" {
 							// Check specific database key already exist.
 							if dbLastBackups, ok := lastBackups[db]; ok {
@@ -172,7 +316,7 @@ func GetGPBackupInfo(historyFile, backupType string, collectDeleted, collectFail
 						}
 					}
 				}
-			} else if dbInList(db, dbInclude) {
+			} else if dbInList(db, config.DBInclude) {
 				// When db is specified in both include and exclude lists, a warning is displayed in the log
 				// and data for this db is not collected.
 				// It is necessary to set zero metric value for this db.
@@ -182,12 +326,56 @@ func GetGPBackupInfo(historyFile, backupType string, collectDeleted, collectFail
 			}
 		}
 		if len(lastBackups) != 0 {
-			getBackupLastMetrics(lastBackups, currentUnixTime, setUpMetricValue, logger)
+			getBackupLastMetrics(cluster, lastBackups, currentUnixTime, setUpMetricValueFun, logger)
 		} else {
 			logger.Warn("No succeed backups")
 		}
-		getExporterStatusMetrics(dbStatus, setUpMetricValue, logger)
+		// RPO and incremental chains are evaluated regardless of backup type filter
+		// and collection depth, so newer backups of other types and old backups aren't lost.
+		recoveryPoints := make(recoveryPointMap, len(stats.recoveryPoints))
+		for db, startTime := range stats.recoveryPoints {
+			if dbSelected(db, config.DBInclude, config.DBExclude) {
+				recoveryPoints[db] = startTime
+			}
+		}
+		incrementalChains := make(map[string]int, len(stats.incrementalChains))
+		for db, length := range stats.incrementalChains {
+			if dbSelected(db, config.DBInclude, config.DBExclude) {
+				incrementalChains[db] = length
+			}
+		}
+		getDatabaseRPOMetrics(cluster, recoveryPoints, currentUnixTime, setUpMetricValueFun, logger)
+		getIncrementalChainMetrics(cluster, incrementalChains, setUpMetricValueFun, logger)
+		getDeletionStateMetrics(cluster, dbDeletionStates, setUpMetricValueFun, logger)
+		getUnrestorableMetrics(cluster, unrestorable, setUpMetricValueFun, logger)
+		getExporterStatusMetrics(cluster, dbStatus, setUpMetricValueFun, logger)
 	} else {
 		logger.Warn("No backup data returned")
 	}
+	// Absence of backups can be reported only when history file is read successfully.
+	// Expected databases are reported even if history file doesn't contain any backups.
+	if err == nil {
+		// Absence of backups, coverage and SLA are evaluated by the last successful backups
+		// regardless of collection depth, so old backups aren't reported as missing.
+		// If such backups can't be got, the last backups within collection depth are used.
+		if stats.lastBackups != nil {
+			lastBackups = make(lastBackupMap, len(stats.lastBackups))
+			for db, backups := range stats.lastBackups {
+				if dbSelected(db, config.DBInclude, config.DBExclude) {
+					lastBackups[db] = backups
+				}
+			}
+		}
+		// Databases from catalog are expected too.
+		// If catalog isn't available, only databases from settings are expected.
+		if info.catalogDBs != nil {
+			getDatabaseCoverageMetrics(cluster, requiredBackupType(config.BackupType), config.SLA, info.catalogDBs, config.DBInclude, config.DBExclude, lastBackups, currentUnixTime, setUpMetricValueFun, logger)
+			dbExpected = mergeDatabases(dbExpected, info.catalogDBs)
+		}
+		getDatabaseMissingMetrics(cluster, requiredBackupType(config.BackupType), dbExpected, config.DBInclude, config.DBExclude, lastBackups, currentUnixTime, setUpMetricValueFun, logger)
+		if !config.SLA.empty() {
+			getBackupSLAMetrics(cluster, config.SLA, slaDatabases(config.SLA, dbStatus, dbExpected, config.DBInclude, config.DBExclude), lastBackups, currentUnixTime, setUpMetricValueFun, logger)
+		}
+	}
+	return stats, err
 }
@@ -4,20 +4,15 @@ import (
 	"log/slog"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 var (
-	gpbckpExporterStatusMetric = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "gpbackup_exporter_status",
-		Help: "gpbackup exporter get data status.",
-	},
-		[]string{"database_name"})
+	gpbckpExporterStatusMetric = newExporterStatusMetric()
 )
 
 // Set exporter metrics:
 //   - gpbackup_exporter_status
-func getExporterStatusMetrics(dbStatus dbStatusMap, setUpMetricValueFun setUpMetricValueFunType, logger *slog.Logger) {
+func getExporterStatusMetrics(cluster string, dbStatus dbStatusMap, setUpMetricValueFun setUpMetricValueFunType, logger *slog.Logger) {
 	for dbName, status := range dbStatus {
 		setUpMetric(
 			gpbckpExporterStatusMetric,
@@ -25,6 +20,7 @@ func getExporterStatusMetrics(dbStatus dbStatusMap, setUpMetricValueFun setUpMet
 			convertBoolToFloat64(status),
 			setUpMetricValueFun,
 			logger,
+			cluster,
 			dbName,
 		)
 	}
@@ -33,3 +29,13 @@ func getExporterStatusMetrics(dbStatus dbStatusMap, setUpMetricValueFun setUpMet
 func resetExporterMetrics() {
 	gpbckpExporterStatusMetric.Reset()
 }
+
+func newExporterStatusMetric() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_exporter_status",
+		Help: "gpbackup exporter get data status.",
+	},
+		[]string{
+			"cluster",
+			"database_name"})
+}
@@ -26,7 +26,7 @@ func TestGetExporterStatusMetrics(t *testing.T) {
 				dbStatusMap{"test": true},
 				`# HELP gpbackup_exporter_status gpbackup exporter get data status.
 # TYPE gpbackup_exporter_status gauge
-gpbackup_exporter_status{database_name="test"} 1
+gpbackup_exporter_status{cluster="",database_name="test"} 1
 `,
 				setUpMetricValue,
 			},
@@ -36,7 +36,7 @@ gpbackup_exporter_status{database_name="test"} 1
 				dbStatusMap{"test": false},
 				`# HELP gpbackup_exporter_status gpbackup exporter get data status.
 # TYPE gpbackup_exporter_status gauge
-gpbackup_exporter_status{database_name="test"} 0
+gpbackup_exporter_status{cluster="",database_name="test"} 0
 `,
 				setUpMetricValue,
 			},
@@ -45,7 +45,7 @@ gpbackup_exporter_status{database_name="test"} 0
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			resetExporterMetrics()
-			getExporterStatusMetrics(tt.args.dbStatus, tt.args.setUpMetricValueFun, getLogger())
+			getExporterStatusMetrics("", tt.args.dbStatus, tt.args.setUpMetricValueFun, getLogger())
 			reg := prometheus.NewRegistry()
 			reg.MustRegister(gpbckpExporterStatusMetric)
 			metricFamily, err := reg.Gather()
@@ -89,7 +89,7 @@ func TestGetExporterStatusErrorsAndDebugs(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			out := &bytes.Buffer{}
 			lc := slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug}))
-			getExporterStatusMetrics(tt.args.dbStatus, tt.args.setUpMetricValueFun, lc)
+			getExporterStatusMetrics("", tt.args.dbStatus, tt.args.setUpMetricValueFun, lc)
 			errorsOutputCount := strings.Count(out.String(), "level=ERROR")
 			debugsOutputCount := strings.Count(out.String(), "level=DEBUG")
 			if tt.args.errorsCount != errorsOutputCount || tt.args.debugsCount != debugsOutputCount {
@@ -2,12 +2,16 @@ package gpbckpexporter
 
 import (
 	"bytes"
+	"context"
 	"log/slog"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/greenplum-db/gpbackup/history"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/prometheus/exporter-toolkit/web"
 	"github.com/woblerr/gpbackman/gpbckpconfig"
 )
@@ -117,14 +121,24 @@ func TestGetGPBackupInfo(t *testing.T) {
 				[]string{""},
 				0,
 			},
-			`level=DEBUG msg="Set up metric" metric=gpbackup_backup_status value=0 labels=metadata-only,test,none,none,20230118162454
-level=DEBUG msg="Set up metric" metric=gpbackup_backup_deletion_status value=0 labels=metadata-only,test,none,none,none,20230118162454
-level=DEBUG msg="Set up metric" metric=gpbackup_backup_info value=1 labels=/data/backups,1.30.5,metadata-only,gzip,test,6.23.0,none,none,none,20230118162454,false
-level=DEBUG msg="Set up metric" metric=gpbackup_backup_duration_seconds value=2 labels=metadata-only,test,20230118162456,none,none,20230118162454
-level=DEBUG msg="Set up metric" metric=gpbackup_backup_status value=0 labels=full,test,none,none,20230118152654
-level=DEBUG msg="Set up metric" metric=gpbackup_backup_deletion_status value=0 labels=full,test,none,none,none,20230118152654
-level=DEBUG msg="Set up metric" metric=gpbackup_backup_info value=1 labels=/data/backups,1.30.5,full,gzip,test,6.23.0,none,none,none,20230118152654,false
-level=DEBUG msg="Set up metric" metric=gpbackup_backup_duration_seconds value=2 labels=full,test,20230118152656,none,none,20230118152654
+			`level=DEBUG msg="Set up metric" metric=gpbackup_backup_status value=0 labels=metadata-only,,test,none,none,20230118162454
+level=DEBUG msg="Set up metric" metric=gpbackup_backup_deletion_status value=0 labels=metadata-only,,test,none,none,none,20230118162454
+level=DEBUG msg="Set up metric" metric=gpbackup_backup_info value=1 labels=/data/backups,1.30.5,metadata-only,,gzip,test,6.23.0,none,none,none,20230118162454,false
+level=DEBUG msg="Set up metric" metric=gpbackup_backup_duration_seconds value=2 labels=metadata-only,,test,20230118162456,none,none,20230118162454
+level=DEBUG msg="Set up metric" metric=gpbackup_backup_restore_chain_length value=1 labels=metadata-only,,test,20230118162454
+level=DEBUG msg="Set up metric" metric=gpbackup_backup_dependent_backups value=0 labels=metadata-only,,test,20230118162454
+level=DEBUG msg="Set up metric" metric=gpbackup_backup_base_full_timestamp_seconds value=1.674059094e+09 labels=metadata-only,,test,20230118162454
+level=DEBUG msg="Set up metric" metric=gpbackup_backup_restorable value=1 labels=metadata-only,,test,20230118162454
+level=DEBUG msg="Set up metric" metric=gpbackup_backup_status value=0 labels=full,,test,none,none,20230118152654
+level=DEBUG msg="Set up metric" metric=gpbackup_backup_deletion_status value=0 labels=full,,test,none,none,none,20230118152654
+level=DEBUG msg="Set up metric" metric=gpbackup_backup_info value=1 labels=/data/backups,1.30.5,full,,gzip,test,6.23.0,none,none,none,20230118152654,false
+level=DEBUG msg="Set up metric" metric=gpbackup_backup_duration_seconds value=2 labels=full,,test,20230118152656,none,none,20230118152654
+level=DEBUG msg="Set up metric" metric=gpbackup_backup_restore_chain_length value=1 labels=full,,test,20230118152654
+level=DEBUG msg="Set up metric" metric=gpbackup_backup_dependent_backups value=0 labels=full,,test,20230118152654
+level=DEBUG msg="Set up metric" metric=gpbackup_backup_base_full_timestamp_seconds value=1.674055614e+09 labels=full,,test,20230118152654
+level=DEBUG msg="Set up metric" metric=gpbackup_backup_restorable value=1 labels=full,,test,20230118152654
+level=DEBUG msg="Set up metric" metric=gpbackup_backup_incremental_chain_length value=0 labels=,test
+level=DEBUG msg="Set up metric" metric=gpbackup_backup_unrestorable_backups value=0 labels=,test
 `,
 		},
 		{
@@ -273,22 +287,134 @@ level=DEBUG msg="Set up metric" metric=gpbackup_backup_duration_seconds value=2
 				},
 			}))
 			GetGPBackupInfo(
+				context.Background(),
+				"",
 				tempFile.Name(),
-				tt.args.bckpType,
-				tt.args.bckpCDeleted,
-				tt.args.bckpCFailed,
-				tt.args.bckpIncl,
-				tt.args.bckpExcl,
-				tt.args.cDepth,
+				CollectConfig{
+					BackupType:     tt.args.bckpType,
+					CollectDeleted: tt.args.bckpCDeleted,
+					CollectFailed:  tt.args.bckpCFailed,
+					DBInclude:      tt.args.bckpIncl,
+					DBExclude:      tt.args.bckpExcl,
+					DBExpected:     []string{""},
+					CollectDepth:   tt.args.cDepth,
+				},
 				lc,
 			)
-			if !strings.Contains(out.String(), tt.testText) {
+			// Values of metrics with current time are skipped.
+			if !containsLinesInOrder(out.String(), tt.testText) {
 				t.Errorf("\nVariable do not match:\n%s\nwant:\n%s", tt.testText, out.String())
 			}
 		})
 	}
 }
 
+func TestGetGPBackupInfoStale(t *testing.T) {
+	defer SetHistoryDBOptions(historyDBOptions)
+	SetHistoryDBOptions(HistoryDBOptions{BusyTimeout: 10 * time.Millisecond})
+	resetCollectionMetrics()
+	defer resetCollectionMetrics()
+	historyFile := fakeHistoryFileBackups(t, templateBackupConfig())
+	defer os.Remove(historyFile)
+	defer delete(historyCaches, historyFile)
+	cluster := "stale"
+	defer gpbckpCollector.update(cluster, nil)
+	GetGPBackupInfo(context.Background(), cluster, historyFile, CollectConfig{}, getLogger())
+	want, ok := gpbckpCollector.snapshot(cluster)
+	if !ok || len(want) == 0 {
+		t.Fatalf("\nVariables do not match:\n%d\nwant:\nnon-empty snapshot", len(want))
+	}
+	unlock := lockHistoryDB(t, historyFile)
+	defer unlock()
+	GetGPBackupInfo(context.Background(), cluster, historyFile, CollectConfig{}, getLogger())
+	got, _ := gpbckpCollector.snapshot(cluster)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, want)
+	}
+	stale := &dto.Metric{}
+	if err := gpbckpSnapshotStaleMetric.WithLabelValues(cluster).Write(stale); err != nil {
+		t.Errorf("\nGet error during write metric:\n%v", err)
+	}
+	if stale.GetGauge().GetValue() != 1 {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", stale.GetGauge().GetValue(), 1)
+	}
+}
+
+func TestCollectBackupInfoStatusFiltered(t *testing.T) {
+	templateMetrics := `# HELP gpbackup_exporter_status gpbackup exporter get data status.
+# TYPE gpbackup_exporter_status gauge
+gpbackup_exporter_status{cluster="",database_name="incr"} 1
+gpbackup_exporter_status{cluster="",database_name="old"} 1
+gpbackup_exporter_status{cluster="",database_name="test"} 1
+`
+	// Backups of "old" database are older than collection depth,
+	// backups of "incr" database don't match backup type filter.
+	// Exporter status is set for these databases too.
+	recent := time.Now().Add(-time.Hour).Format(gpbckpconfig.Layout)
+	full := templateBackupConfig()
+	full.Timestamp = recent
+	full.EndTime = recent
+	old := templateBackupConfig()
+	old.DatabaseName = "old"
+	old.Timestamp = time.Now().AddDate(0, 0, -10).Format(gpbckpconfig.Layout)
+	old.EndTime = old.Timestamp
+	incr := templateBackupConfig()
+	incr.DatabaseName = "incr"
+	incr.Incremental = true
+	incr.Timestamp = time.Now().Add(-2 * time.Hour).Format(gpbckpconfig.Layout)
+	incr.EndTime = incr.Timestamp
+	historyFile := fakeHistoryFileBackups(t, full, old, incr)
+	defer os.Remove(historyFile)
+	defer delete(historyCaches, historyFile)
+	resetMetrics()
+	config := CollectConfig{BackupType: "full", CollectDepth: 7}
+	if _, err := collectBackupInfo(context.Background(), "", historyFile, config, nil, setUpMetricValue, getLogger()); err != nil {
+		t.Fatalf("\nGet error during collect backup info:\n%v", err)
+	}
+	if got := gatherMetricsText(t, gpbckpExporterStatusMetric); got != templateMetrics {
+		t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", got, templateMetrics)
+	}
+}
+
+func TestGetGPBackupInfoRetryWithoutMutex(t *testing.T) {
+	defer SetHistoryDBOptions(historyDBOptions)
+	SetHistoryDBOptions(HistoryDBOptions{BusyTimeout: 10 * time.Millisecond, LockRetries: 1, LockRetryBackoff: time.Second})
+	resetCollectionMetrics()
+	defer resetCollectionMetrics()
+	historyFile := fakeHistoryFileBackups(t, templateBackupConfig())
+	defer os.Remove(historyFile)
+	defer delete(historyCaches, historyFile)
+	cluster := "retry"
+	defer gpbckpCollector.update(cluster, nil)
+	unlock := lockHistoryDB(t, historyFile)
+	defer unlock()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		GetGPBackupInfo(context.Background(), cluster, historyFile, CollectConfig{}, getLogger())
+	}()
+	// Collection waits for retry, other collections aren't blocked.
+	time.Sleep(200 * time.Millisecond)
+	if !collectMutex.TryLock() {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", false, true)
+	} else {
+		collectMutex.Unlock()
+	}
+	<-done
+}
+
+// Check that text contains all lines in the same order, other lines are allowed between them.
+func containsLinesInOrder(text, lines string) bool {
+	for _, line := range strings.Split(strings.TrimSuffix(lines, "\n"), "\n") {
+		i := strings.Index(text, line)
+		if i < 0 {
+			return false
+		}
+		text = text[i+len(line):]
+	}
+	return true
+}
+
 func fakeHistoryFileData(text string) (*os.File, error) {
 	// Create a temporary SQLite file
 	tempFile, err := os.CreateTemp("", "gpbackup_history*.db")
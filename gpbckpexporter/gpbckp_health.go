@@ -0,0 +1,174 @@
+package gpbckpexporter
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Endpoints for liveness and readiness checks.
+const (
+	healthyEndpoint = "/-/healthy"
+	readyEndpoint   = "/-/ready"
+)
+
+// Collection health of history source.
+type sourceHealth struct {
+	Cluster       string    `json:"cluster"`
+	LastSuccess   time.Time `json:"last_success,omitzero"`
+	LastError     string    `json:"last_error"`
+	LastErrorTime time.Time `json:"last_error_time,omitzero"`
+}
+
+// Response body for liveness and readiness checks.
+type healthResponse struct {
+	Status    string         `json:"status"`
+	LastError string         `json:"last_error"`
+	Sources   []sourceHealth `json:"sources,omitempty"`
+}
+
+// Collection health of all history sources for periodic collection.
+type collectionHealth struct {
+	mu      sync.Mutex
+	sources map[string]*sourceHealth
+	// Exporter isn't ready, when the last successful collection is older than this value.
+	// 0 - without limit.
+	maxAge time.Duration
+	// Closed, when the first collection for all sources is finished.
+	collected chan struct{}
+	// Sources, which have been collected since the last complete cycle, regardless of errors.
+	cycle map[string]struct{}
+}
+
+var gpbckpHealth = newCollectionHealth(nil, 0)
+
+func newCollectionHealth(clusters []string, maxAge time.Duration) *collectionHealth {
+	h := &collectionHealth{
+		sources:   make(map[string]*sourceHealth, len(clusters)),
+		maxAge:    maxAge,
+		collected: make(chan struct{}),
+		cycle:     make(map[string]struct{}, len(clusters)),
+	}
+	for _, cluster := range clusters {
+		h.sources[cluster] = &sourceHealth{Cluster: cluster}
+	}
+	if len(h.sources) == 0 {
+		close(h.collected)
+	}
+	return h
+}
+
+// SetHealthSources sets history sources, which must be collected successfully
+// for readiness, and maximum age of the last successful collection.
+func SetHealthSources(clusters []string, maxAge time.Duration) {
+	gpbckpHealth = newCollectionHealth(clusters, maxAge)
+}
+
+// Save result of collection for history source.
+// Returns true, when collections for all sources have been finished
+// since the previous complete cycle, regardless of errors.
+// So collection loop liveness is reported separately from health of sources.
+// Results for unknown sources are ignored.
+func (h *collectionHealth) record(cluster string, err error) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	source, ok := h.sources[cluster]
+	if !ok {
+		return false
+	}
+	if err != nil {
+		source.LastError = err.Error()
+		source.LastErrorTime = time.Now()
+	} else {
+		source.LastSuccess = time.Now()
+	}
+	h.cycle[cluster] = struct{}{}
+	h.checkFirstCollection()
+	if len(h.cycle) != len(h.sources) {
+		return false
+	}
+	clear(h.cycle)
+	return true
+}
+
+// Close channel for the first collection, when all sources have been collected.
+// Must be called with mu held.
+func (h *collectionHealth) checkFirstCollection() {
+	select {
+	case <-h.collected:
+		return
+	default:
+	}
+	for _, source := range h.sources {
+		if source.LastSuccess.IsZero() && source.LastErrorTime.IsZero() {
+			return
+		}
+	}
+	close(h.collected)
+}
+
+// Get channel, which is closed, when the first collection for all sources is finished.
+func (h *collectionHealth) firstCollection() <-chan struct{} {
+	return h.collected
+}
+
+// Get current state of all sources and check readiness.
+// Exporter is ready, when all sources have been collected successfully
+// and the last successful collections aren't too old.
+func (h *collectionHealth) state() (healthResponse, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var (
+		response      healthResponse
+		lastErrorTime time.Time
+	)
+	ready := true
+	for _, source := range h.sources {
+		if source.LastSuccess.IsZero() || (h.maxAge > 0 && time.Since(source.LastSuccess) > h.maxAge) {
+			ready = false
+		}
+		if source.LastErrorTime.After(lastErrorTime) {
+			lastErrorTime = source.LastErrorTime
+			response.LastError = source.LastError
+		}
+		response.Sources = append(response.Sources, *source)
+	}
+	sort.Slice(response.Sources, func(i, j int) bool {
+		return response.Sources[i].Cluster < response.Sources[j].Cluster
+	})
+	return response, ready
+}
+
+// Handler for liveness check, it always reports that exporter is alive.
+func healthyHandler(health *collectionHealth, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response, _ := health.state()
+		response.Status = "healthy"
+		writeHealthResponse(w, http.StatusOK, response, logger)
+	})
+}
+
+// Handler for readiness check.
+func readyHandler(health *collectionHealth, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response, ready := health.state()
+		if !ready {
+			response.Status = "not ready"
+			writeHealthResponse(w, http.StatusServiceUnavailable, response, logger)
+			return
+		}
+		response.Status = "ready"
+		writeHealthResponse(w, http.StatusOK, response, logger)
+	})
+}
+
+func writeHealthResponse(w http.ResponseWriter, code int, response healthResponse, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Error("Write health response failed", "err", err)
+	}
+}
@@ -0,0 +1,172 @@
+package gpbckpexporter
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthHandlers(t *testing.T) {
+	tests := []struct {
+		name          string
+		clusters      []string
+		maxAge        time.Duration
+		results       map[string][]error
+		lastSuccessAt time.Duration
+		wantReadyCode int
+		wantLastError string
+	}{
+		{
+			"NoCollections",
+			[]string{""},
+			time.Minute,
+			nil,
+			0,
+			http.StatusServiceUnavailable,
+			"",
+		},
+		{
+			"SuccessfulCollection",
+			[]string{""},
+			time.Minute,
+			map[string][]error{"": {nil}},
+			0,
+			http.StatusOK,
+			"",
+		},
+		{
+			"FailedCollectionAfterSuccess",
+			[]string{""},
+			time.Minute,
+			map[string][]error{"": {nil, errors.New("database is locked")}},
+			0,
+			http.StatusOK,
+			"database is locked",
+		},
+		{
+			"OnlyOneSourceCollected",
+			[]string{"cluster1", "cluster2"},
+			time.Minute,
+			map[string][]error{"cluster1": {nil}, "cluster2": {errors.New("no such file or directory")}},
+			0,
+			http.StatusServiceUnavailable,
+			"no such file or directory",
+		},
+		{
+			"StaleCollection",
+			[]string{""},
+			time.Minute,
+			map[string][]error{"": {nil}},
+			-2 * time.Minute,
+			http.StatusServiceUnavailable,
+			"",
+		},
+		{
+			"StaleCheckDisabled",
+			[]string{""},
+			0,
+			map[string][]error{"": {nil}},
+			-2 * time.Minute,
+			http.StatusOK,
+			"",
+		},
+		{
+			"WithoutSources",
+			nil,
+			time.Minute,
+			nil,
+			0,
+			http.StatusOK,
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health := newCollectionHealth(tt.clusters, tt.maxAge)
+			for cluster, errs := range tt.results {
+				for _, err := range errs {
+					health.record(cluster, err)
+				}
+				health.sources[cluster].LastSuccess = health.sources[cluster].LastSuccess.Add(tt.lastSuccessAt)
+			}
+			for _, check := range []struct {
+				handler  http.Handler
+				wantCode int
+			}{
+				{healthyHandler(health, getLogger()), http.StatusOK},
+				{readyHandler(health, getLogger()), tt.wantReadyCode},
+			} {
+				rec := httptest.NewRecorder()
+				check.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+				if rec.Code != check.wantCode {
+					t.Errorf("\nVariables do not match:\n%d\nwant:\n%d", rec.Code, check.wantCode)
+				}
+				if got := rec.Header().Get("Content-Type"); got != "application/json" {
+					t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", got, "application/json")
+				}
+				var response healthResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+					t.Fatalf("\nGet error during unmarshal response:\n%v", err)
+				}
+				if response.LastError != tt.wantLastError {
+					t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", response.LastError, tt.wantLastError)
+				}
+				if len(response.Sources) != len(tt.clusters) {
+					t.Errorf("\nVariables do not match:\n%d\nwant:\n%d", len(response.Sources), len(tt.clusters))
+				}
+			}
+		})
+	}
+}
+
+func TestCollectionHealthRecordUnknownSource(t *testing.T) {
+	health := newCollectionHealth(nil, 0)
+	health.record("unknown", nil)
+	if len(health.sources) != 0 {
+		t.Errorf("\nVariables do not match:\n%d\nwant:\n%d", len(health.sources), 0)
+	}
+}
+
+func TestCollectionHealthFirstCollection(t *testing.T) {
+	health := newCollectionHealth([]string{"cluster1", "cluster2"}, 0)
+	health.record("cluster1", nil)
+	select {
+	case <-health.firstCollection():
+		t.Errorf("\nFirst collection is finished before all sources are collected")
+	default:
+	}
+	// Failed collection finishes the first collection too.
+	health.record("cluster2", errors.New("database is locked"))
+	health.record("cluster2", nil)
+	select {
+	case <-health.firstCollection():
+	default:
+		t.Errorf("\nFirst collection isn't finished after all sources are collected")
+	}
+}
+
+func TestCollectionHealthRecordCycle(t *testing.T) {
+	health := newCollectionHealth([]string{"cluster1", "cluster2"}, 0)
+	tests := []struct {
+		cluster       string
+		err           error
+		wantCycleDone bool
+	}{
+		{"cluster1", nil, false},
+		{"cluster1", nil, false},
+		// Failed collection completes cycle too.
+		{"cluster2", errors.New("database is locked"), true},
+		// New cycle is started after complete one.
+		{"cluster2", nil, false},
+		{"cluster1", errors.New("no such file or directory"), true},
+		{"unknown", nil, false},
+	}
+	for i, tt := range tests {
+		if got := health.record(tt.cluster, tt.err); got != tt.wantCycleDone {
+			t.Errorf("\nVariables do not match for record %d:\n%v\nwant:\n%v", i, got, tt.wantCycleDone)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package gpbckpexporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+// Maximum number of timestamps in one query for loading backups.
+const loadBatchSize = 500
+
+// Rows in history database are immutable except for status, end_time and date_deleted.
+// So parsed backup configs are cached between collections.
+// On each collection mutable fields are read only for non-terminal backups:
+// backups in progress and backups, which aren't deleted yet or deletion of which isn't finished.
+// Any backup can be deleted via gpbackman at any time, so active backups are non-terminal too.
+// Full data (including aux tables and restore plans) is read only for new backups.
+type backupCache struct {
+	// Serializes loads of the same history file by several collections.
+	mu sync.Mutex
+	// History file info to detect file replacement.
+	fileInfo os.FileInfo
+	// Collection depth limit, which backups were loaded with.
+	timestampAfter string
+	// Backup configs by timestamp.
+	backups map[string]gpbckpconfig.BackupConfig
+}
+
+// Caches by history file path and filter key.
+// Collections with different filters (for example, probes) for the same history file
+// have their own caches, so they don't reload each other's backups.
+// History files are read without collectMutex held, so access is serialized
+// via historyCachesMutex for map and via cache mutex for each cache.
+var (
+	historyCaches      = make(map[string]map[string]*backupCache)
+	historyCachesMutex sync.Mutex
+)
+
+// Get cache for history file and filter, new empty cache is created on the first call.
+func getHistoryCache(historyFile string, filter backupFilter) *backupCache {
+	historyCachesMutex.Lock()
+	defer historyCachesMutex.Unlock()
+	fileCaches, ok := historyCaches[historyFile]
+	if !ok {
+		fileCaches = make(map[string]*backupCache)
+		historyCaches[historyFile] = fileCaches
+	}
+	key := filter.cacheKey()
+	cache, ok := fileCaches[key]
+	if !ok {
+		cache = &backupCache{}
+		fileCaches[key] = cache
+	}
+	return cache
+}
+
+// Get key of cache for filter.
+// Collection depth limit moves with time, so it isn't part of the key.
+func (f backupFilter) cacheKey() string {
+	return fmt.Sprintf("%t|%t|%s|%q|%q", f.collectDeleted, f.collectFailed, f.backupType, f.dbInclude, f.dbExclude)
+}
+
+const backupStatesQuery = `
+SELECT b.timestamp, b.status, b.end_time, b.date_deleted
+FROM backups b
+%s
+ORDER BY b.timestamp DESC;`
+
+const backupsCountQuery = `
+SELECT COUNT(*)
+FROM backups b
+%s;`
+
+// Mutable fields of backup.
+type backupState struct {
+	timestamp   string
+	status      string
+	endTime     string
+	dateDeleted string
+}
+
+// Check that mutable fields of backup can't be changed anymore:
+// backup is finished and its deletion is finished.
+func isBackupTerminal(backupConfig gpbckpconfig.BackupConfig) bool {
+	return !backupConfig.IsInProgress() &&
+		!gpbckpconfig.IsBackupActive(backupConfig.DateDeleted) &&
+		backupConfig.DateDeleted != gpbckpconfig.DateDeletedInProgress
+}
+
+// Load backup configs from history database using cache.
+// Backup configs are sorted by timestamp in descending order.
+// Returns number of rows read from backups table, for incremental load
+// it's number of non-terminal and new backups.
+// Full reload is performed, when history file is replaced, collection depth is increased
+// or history database is changed not only by adding backups
+// (for example, backups are removed from history via gpbackman).
+func loadBackupConfigsCached(ctx context.Context, historyFile string, hDB *sql.DB, filter backupFilter, logger *slog.Logger) ([]gpbckpconfig.BackupConfig, int, error) {
+	cache := getHistoryCache(historyFile, filter)
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	fileInfo, err := os.Stat(historyFile)
+	if err != nil {
+		cache.reset()
+		return nil, 0, err
+	}
+	rowsRead := 0
+	if cache.fileInfo != nil && os.SameFile(cache.fileInfo, fileInfo) && filter.timestampAfter >= cache.timestampAfter {
+		var consistent bool
+		// On error cached backups are still valid, they are updated on the next load.
+		rowsRead, consistent, err = cache.update(ctx, hDB, filter, logger.With("file", historyFile))
+		if err != nil {
+			return nil, 0, err
+		}
+		if consistent {
+			cache.fileInfo = fileInfo
+			return cache.backupConfigs(), rowsRead, nil
+		}
+		logger.Debug("History database is changed, cache is dropped", "file", historyFile)
+	}
+	logger.Debug("Full load of history database", "file", historyFile)
+	cache.reset()
+	backupConfigs, err := loadBackupConfigsDB(ctx, hDB, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	cache.fileInfo = fileInfo
+	cache.timestampAfter = filter.timestampAfter
+	cache.backups = make(map[string]gpbckpconfig.BackupConfig, len(backupConfigs))
+	for _, backupConfig := range backupConfigs {
+		cache.backups[backupConfig.Timestamp] = backupConfig
+	}
+	return backupConfigs, rowsRead + len(backupConfigs), nil
+}
+
+// Update cached backups: drop backups older than collection depth,
+// refresh mutable fields of non-terminal backups and load new backups.
+// Returns number of rows read and false, if cached backups don't match
+// backups selected from history database.
+func (c *backupCache) update(ctx context.Context, hDB *sql.DB, filter backupFilter, logger *slog.Logger) (int, bool, error) {
+	var (
+		rowsRead      int
+		lastTimestamp string
+		nonTerminal   []string
+	)
+	for timestamp, backupConfig := range c.backups {
+		if filter.timestampAfter != "" && timestamp <= filter.timestampAfter {
+			delete(c.backups, timestamp)
+			continue
+		}
+		lastTimestamp = max(lastTimestamp, timestamp)
+		if !isBackupTerminal(backupConfig) {
+			nonTerminal = append(nonTerminal, timestamp)
+		}
+	}
+	c.timestampAfter = filter.timestampAfter
+	for i := 0; i < len(nonTerminal); i += loadBatchSize {
+		batch := nonTerminal[i:min(i+loadBatchSize, len(nonTerminal))]
+		batchFilter := filter
+		batchFilter.timestamps = batch
+		states, err := loadBackupStatesDB(ctx, hDB, batchFilter)
+		if err != nil {
+			return 0, false, err
+		}
+		rowsRead += len(states)
+		selected := make(map[string]backupState, len(states))
+		for _, state := range states {
+			selected[state.timestamp] = state
+		}
+		for _, timestamp := range batch {
+			state, ok := selected[timestamp]
+			// Backups, which are not selected anymore, are removed from cache.
+			if !ok {
+				delete(c.backups, timestamp)
+				continue
+			}
+			backupConfig := c.backups[timestamp]
+			backupConfig.Status = state.status
+			backupConfig.EndTime = state.endTime
+			backupConfig.DateDeleted = state.dateDeleted
+			c.backups[timestamp] = backupConfig
+		}
+	}
+	// Backups are added to history with increasing timestamps.
+	newFilter := filter
+	newFilter.timestampAfter = max(filter.timestampAfter, lastTimestamp)
+	backupConfigs, err := loadBackupConfigsDB(ctx, hDB, newFilter)
+	if err != nil {
+		return 0, false, err
+	}
+	rowsRead += len(backupConfigs)
+	for _, backupConfig := range backupConfigs {
+		c.backups[backupConfig.Timestamp] = backupConfig
+	}
+	logger.Debug(
+		"Incremental load of history database",
+		"cached", len(c.backups)-len(backupConfigs),
+		"refreshed", len(nonTerminal),
+		"new", len(backupConfigs),
+	)
+	// Terminal backups aren't read, so removed backups
+	// or backups added with older timestamps are detected by number of selected backups.
+	count, err := loadBackupsCountDB(ctx, hDB, filter)
+	if err != nil {
+		return 0, false, err
+	}
+	return rowsRead, count == len(c.backups), nil
+}
+
+// Get cached backup configs sorted by timestamp in descending order.
+func (c *backupCache) backupConfigs() []gpbckpconfig.BackupConfig {
+	backupConfigs := make([]gpbckpconfig.BackupConfig, 0, len(c.backups))
+	for _, backupConfig := range c.backups {
+		backupConfigs = append(backupConfigs, backupConfig)
+	}
+	sort.Slice(backupConfigs, func(i, j int) bool {
+		return backupConfigs[i].Timestamp > backupConfigs[j].Timestamp
+	})
+	return backupConfigs
+}
+
+// Drop cached backups, so the next load is full.
+func (c *backupCache) reset() {
+	c.fileInfo = nil
+	c.timestampAfter = ""
+	c.backups = nil
+}
+
+// Load mutable fields for selected backups.
+func loadBackupStatesDB(ctx context.Context, hDB *sql.DB, filter backupFilter) ([]backupState, error) {
+	where, args := filter.whereClause()
+	rows, err := hDB.QueryContext(ctx, fmt.Sprintf(backupStatesQuery, where), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	states := make([]backupState, 0)
+	for rows.Next() {
+		var state backupState
+		if err := rows.Scan(&state.timestamp, &state.status, &state.endTime, &state.dateDeleted); err != nil {
+			return nil, newRowDecodeError(err)
+		}
+		states = append(states, state)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+// Load number of selected backups.
+func loadBackupsCountDB(ctx context.Context, hDB *sql.DB, filter backupFilter) (int, error) {
+	where, args := filter.whereClause()
+	var count int
+	if err := hDB.QueryRowContext(ctx, fmt.Sprintf(backupsCountQuery, where), args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
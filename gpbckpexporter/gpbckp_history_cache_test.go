@@ -0,0 +1,186 @@
+package gpbckpexporter
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/greenplum-db/gpbackup/history"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+func TestLoadBackupConfigsCached(t *testing.T) {
+	firstBackup := templateBackupConfig()
+	secondBackup := templateBackupConfig()
+	secondBackup.Timestamp = "20230119152654"
+	secondBackup.EndTime = "20230119152656"
+	secondBackup.Incremental = true
+	secondBackup.RestorePlan = []gpbckpconfig.RestorePlanEntry{
+		{Timestamp: "20230118152654", TableFQNs: []string{"public.t1"}},
+		{Timestamp: "20230119152654", TableFQNs: []string{"public.t1"}},
+	}
+	thirdBackup := templateBackupConfig()
+	thirdBackup.Timestamp = "20230120152654"
+	thirdBackup.EndTime = "20230120152656"
+	historyFile := fakeHistoryFileBackups(t, firstBackup, secondBackup)
+	defer os.Remove(historyFile)
+	defer delete(historyCaches, historyFile)
+	filter := backupFilter{collectDeleted: true}
+	tests := []struct {
+		name     string
+		prepare  func(t *testing.T)
+		rowsRead int
+	}{
+		{
+			"FullLoad",
+			func(t *testing.T) {},
+			2,
+		},
+		{
+			"NewBackupAndDeletedBackup",
+			func(t *testing.T) {
+				hDB, err := gpbckpconfig.OpenHistoryDB(historyFile)
+				if err != nil {
+					t.Fatalf("Failed to open test database: %v", err)
+				}
+				defer hDB.Close()
+				hBackupConfig := gpbckpconfig.ConvertToHistoryBackupConfig(thirdBackup)
+				if err := history.StoreBackupHistory(hDB, &hBackupConfig); err != nil {
+					t.Fatalf("Failed to store backup history: %v", err)
+				}
+				if err := gpbckpconfig.UpdateDeleteStatus(firstBackup.Timestamp, "20230121152654", hDB); err != nil {
+					t.Fatalf("Failed to update backup: %v", err)
+				}
+				if err := gpbckpconfig.UpdateDeleteStatus(secondBackup.Timestamp, gpbckpconfig.DateDeletedInProgress, hDB); err != nil {
+					t.Fatalf("Failed to update backup: %v", err)
+				}
+			},
+			// States of both non-terminal backups and full data of new backup.
+			3,
+		},
+		{
+			"TerminalBackupSkipped",
+			func(t *testing.T) {},
+			// States of backup with deletion in progress and of active backup.
+			2,
+		},
+		{
+			"BackupRemoved",
+			func(t *testing.T) {
+				hDB, err := gpbckpconfig.OpenHistoryDB(historyFile)
+				if err != nil {
+					t.Fatalf("Failed to open test database: %v", err)
+				}
+				defer hDB.Close()
+				if _, err := hDB.Exec("DELETE FROM backups WHERE timestamp = ?", firstBackup.Timestamp); err != nil {
+					t.Fatalf("Failed to delete backup: %v", err)
+				}
+			},
+			// States of non-terminal backups and full reload.
+			4,
+		},
+		{
+			"FileReplaced",
+			func(t *testing.T) {
+				newHistoryFile := fakeHistoryFileBackups(t, firstBackup)
+				if err := os.Rename(newHistoryFile, historyFile); err != nil {
+					t.Fatalf("Failed to replace history file: %v", err)
+				}
+			},
+			1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.prepare(t)
+			hDB, err := gpbckpconfig.OpenHistoryDB(historyFile)
+			if err != nil {
+				t.Fatalf("Failed to open test database: %v", err)
+			}
+			defer hDB.Close()
+			got, rowsRead, err := loadBackupConfigsCached(context.Background(), historyFile, hDB, filter, getLogger())
+			if err != nil {
+				t.Fatalf("\nGet error during load backups:\n%v", err)
+			}
+			want, err := loadBackupConfigsDB(context.Background(), hDB, filter)
+			if err != nil {
+				t.Fatalf("\nGet error during load backups:\n%v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, want)
+			}
+			if rowsRead != tt.rowsRead {
+				t.Errorf("\nVariables do not match:\n%d\nwant:\n%d", rowsRead, tt.rowsRead)
+			}
+			if cache := getHistoryCache(historyFile, filter); len(cache.backups) != len(want) {
+				t.Errorf("\nVariables do not match:\n%d\nwant:\n%d", len(cache.backups), len(want))
+			}
+		})
+	}
+}
+
+func TestLoadBackupConfigsCachedNoFile(t *testing.T) {
+	historyFile := "/nonexistent/path/to/db.db"
+	defer delete(historyCaches, historyFile)
+	cache := getHistoryCache(historyFile, backupFilter{})
+	cache.backups = map[string]gpbckpconfig.BackupConfig{"20230118152654": templateBackupConfig()}
+	_, _, err := loadBackupConfigsCached(context.Background(), historyFile, nil, backupFilter{}, getLogger())
+	if err == nil {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\nerror", err)
+	}
+	if cache.fileInfo != nil || cache.backups != nil {
+		t.Errorf("\nCache for %s was not dropped", historyFile)
+	}
+}
+
+func TestLoadBackupConfigsCachedFilters(t *testing.T) {
+	fullBackup := templateBackupConfig()
+	incrBackup := templateBackupConfig()
+	incrBackup.Timestamp = "20230119152654"
+	incrBackup.EndTime = "20230119152656"
+	incrBackup.Incremental = true
+	oldBackup := templateBackupConfig()
+	oldBackup.Timestamp = "20230110152654"
+	oldBackup.EndTime = "20230110152656"
+	historyFile := fakeHistoryFileBackups(t, fullBackup, incrBackup, oldBackup)
+	defer os.Remove(historyFile)
+	defer delete(historyCaches, historyFile)
+	hDB, err := gpbckpconfig.OpenHistoryDB(historyFile)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer hDB.Close()
+	tests := []struct {
+		name     string
+		filter   backupFilter
+		rowsRead int
+	}{
+		{"FullLoadAll", backupFilter{}, 3},
+		{"FullLoadFull", backupFilter{backupType: "full"}, 2},
+		// Caches for different filters don't reload each other.
+		{"IncrementalAll", backupFilter{}, 3},
+		{"IncrementalFull", backupFilter{backupType: "full"}, 2},
+		// Backups older than collection depth are dropped from cache.
+		{"DepthDecreased", backupFilter{timestampAfter: "20230115000000"}, 2},
+		{"DepthIncreased", backupFilter{timestampAfter: "20230101000000"}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rowsRead, err := loadBackupConfigsCached(context.Background(), historyFile, hDB, tt.filter, getLogger())
+			if err != nil {
+				t.Fatalf("\nGet error during load backups:\n%v", err)
+			}
+			want, err := loadBackupConfigsDB(context.Background(), hDB, tt.filter)
+			if err != nil {
+				t.Fatalf("\nGet error during load backups:\n%v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, want)
+			}
+			if rowsRead != tt.rowsRead {
+				t.Errorf("\nVariables do not match:\n%d\nwant:\n%d", rowsRead, tt.rowsRead)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package gpbckpexporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+// HistoryDBOptions contains settings for access to history database.
+// History database can be locked by gpbackup or gpbackman for a long time,
+// so reading is retried with backoff when database is locked.
+// For shared storages with unreliable locking, copy of history file can be read instead.
+type HistoryDBOptions struct {
+	// Time to wait for lock within one attempt.
+	BusyTimeout time.Duration
+	// Number of additional attempts, when database is locked.
+	LockRetries int
+	// Delay before the first retry, it's doubled for each next retry.
+	LockRetryBackoff time.Duration
+	// Read consistent copy of history file instead of original file.
+	SnapshotCopy bool
+	// Directory for copies of history file.
+	// Empty value means default directory for temporary files.
+	SnapshotDir string
+}
+
+var historyDBOptions = HistoryDBOptions{
+	BusyTimeout:      5 * time.Second,
+	LockRetries:      3,
+	LockRetryBackoff: time.Second,
+}
+
+// SetHistoryDBOptions sets settings for access to history database
+// from command line arguments:
+// 'gpbackup.busy-timeout',
+// 'gpbackup.lock-retries',
+// 'gpbackup.lock-retry-backoff',
+// 'gpbackup.snapshot-copy',
+// 'gpbackup.snapshot-dir'
+func SetHistoryDBOptions(options HistoryDBOptions) {
+	historyDBOptions = options
+}
+
+// Validate checks settings for access to history database.
+func (o HistoryDBOptions) Validate() error {
+	if o.BusyTimeout < 0 {
+		return fmt.Errorf("busy timeout must not be negative, got %v", o.BusyTimeout)
+	}
+	if o.LockRetries < 0 {
+		return fmt.Errorf("lock retries must not be negative, got %d", o.LockRetries)
+	}
+	if o.LockRetryBackoff < 0 {
+		return fmt.Errorf("lock retry backoff must not be negative, got %v", o.LockRetryBackoff)
+	}
+	if o.SnapshotCopy && o.SnapshotDir != "" {
+		info, err := os.Stat(o.SnapshotDir)
+		if err != nil {
+			return fmt.Errorf("snapshot dir: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("snapshot dir %s is not a directory", o.SnapshotDir)
+		}
+	}
+	return nil
+}
+
+// Open history database in read-only mode with busy timeout.
+// Unlike gpbckpconfig.OpenHistoryDB, missing file is not created.
+func openHistoryDB(historyFile string, busyTimeout time.Duration) (*sql.DB, error) {
+	dsn := fmt.Sprintf(
+		"file:%s?mode=ro&_busy_timeout=%d",
+		(&url.URL{Path: historyFile}).EscapedPath(),
+		busyTimeout.Milliseconds(),
+	)
+	return sql.Open("sqlite3", dsn)
+}
+
+// Get data from history database with retries, when database is locked
+// or history file is changed during copying.
+func getDataFromHistoryDBWithRetries(ctx context.Context, historyFile string, filter backupFilter, logger *slog.Logger) (gpbckpconfig.History, []string, historyStats, error) {
+	options := historyDBOptions
+	backoff := options.LockRetryBackoff
+	for attempt := 0; ; attempt++ {
+		hData, dbNames, stats, err := getDataFromHistoryDB(ctx, historyFile, filter, logger)
+		if err == nil || !isBusyError(err) || attempt >= options.LockRetries {
+			return hData, dbNames, stats, err
+		}
+		logger.Warn(
+			"History db is busy, retry",
+			"attempt", attempt+1,
+			"retries", options.LockRetries,
+			"backoff", backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return hData, dbNames, stats, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
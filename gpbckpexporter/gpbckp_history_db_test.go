@@ -0,0 +1,140 @@
+package gpbckpexporter
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+func TestOpenHistoryDB(t *testing.T) {
+	dir := t.TempDir()
+	historyFile := filepath.Join(dir, "gpbackup history.db")
+	tempFile := fakeHistoryFileBackups(t, templateBackupConfig())
+	if err := os.Rename(tempFile, historyFile); err != nil {
+		t.Fatalf("Failed to rename test file: %v", err)
+	}
+	hDB, err := openHistoryDB(historyFile, time.Second)
+	if err != nil {
+		t.Fatalf("\nGet error during open history db:\n%v", err)
+	}
+	defer hDB.Close()
+	var count int
+	if err := hDB.QueryRow("SELECT COUNT(*) FROM backups;").Scan(&count); err != nil {
+		t.Fatalf("\nGet error during query:\n%v", err)
+	}
+	if count != 1 {
+		t.Errorf("\nVariables do not match:\n%d\nwant:\n%d", count, 1)
+	}
+	if _, err := hDB.Exec("DELETE FROM backups;"); err == nil {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\nerror for read-only database", err)
+	}
+	// Missing file is not created.
+	missingFile := filepath.Join(dir, "missing.db")
+	missingDB, err := openHistoryDB(missingFile, time.Second)
+	if err != nil {
+		t.Fatalf("\nGet error during open history db:\n%v", err)
+	}
+	defer missingDB.Close()
+	if err := missingDB.Ping(); err == nil {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\nerror for missing database", err)
+	}
+	if _, err := os.Stat(missingFile); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", err, os.ErrNotExist)
+	}
+}
+
+func TestGetDataFromHistoryDBWithRetries(t *testing.T) {
+	defer SetHistoryDBOptions(historyDBOptions)
+	tests := []struct {
+		name      string
+		options   HistoryDBOptions
+		unlockIn  time.Duration
+		wantError error
+	}{
+		{
+			"LockedAfterRetries",
+			HistoryDBOptions{BusyTimeout: 10 * time.Millisecond, LockRetries: 2, LockRetryBackoff: 10 * time.Millisecond},
+			0,
+			errDatabaseLocked,
+		},
+		{
+			"UnlockedDuringRetries",
+			HistoryDBOptions{BusyTimeout: 10 * time.Millisecond, LockRetries: 5, LockRetryBackoff: 20 * time.Millisecond},
+			50 * time.Millisecond,
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			historyFile := fakeHistoryFileBackups(t, templateBackupConfig())
+			defer os.Remove(historyFile)
+			defer delete(historyCaches, historyFile)
+			unlock := lockHistoryDB(t, historyFile)
+			defer unlock()
+			if tt.unlockIn != 0 {
+				time.AfterFunc(tt.unlockIn, unlock)
+			}
+			SetHistoryDBOptions(tt.options)
+			hData, _, _, err := getDataFromHistoryDBWithRetries(context.Background(), historyFile, backupFilter{}, getLogger())
+			if !errors.Is(err, tt.wantError) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", err, tt.wantError)
+			}
+			if tt.wantError == nil && len(hData.BackupConfigs) != 1 {
+				t.Errorf("\nVariables do not match:\n%d\nwant:\n%d", len(hData.BackupConfigs), 1)
+			}
+		})
+	}
+}
+
+func TestHistoryDBOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options HistoryDBOptions
+		wantErr bool
+	}{
+		{"Valid", HistoryDBOptions{BusyTimeout: time.Second, LockRetries: 3, LockRetryBackoff: time.Second}, false},
+		{"WithoutRetries", HistoryDBOptions{}, false},
+		{"NegativeBusyTimeout", HistoryDBOptions{BusyTimeout: -time.Second}, true},
+		{"NegativeRetries", HistoryDBOptions{LockRetries: -1}, true},
+		{"NegativeBackoff", HistoryDBOptions{LockRetryBackoff: -time.Second}, true},
+		{"SnapshotDir", HistoryDBOptions{SnapshotCopy: true, SnapshotDir: os.TempDir()}, false},
+		{"MissingSnapshotDir", HistoryDBOptions{SnapshotCopy: true, SnapshotDir: "/nonexistent/path"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.options.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("\nVariables do not match:\n%v\nwantErr:\n%v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Take exclusive lock on history database, like gpbackup does during writing.
+// Returned function releases the lock, it can be called several times.
+func lockHistoryDB(tb testing.TB, historyFile string) func() {
+	hDB, err := gpbckpconfig.OpenHistoryDB(historyFile)
+	if err != nil {
+		tb.Fatalf("Failed to open test database: %v", err)
+	}
+	conn, err := hDB.Conn(context.Background())
+	if err != nil {
+		tb.Fatalf("Failed to get connection: %v", err)
+	}
+	if _, err := conn.ExecContext(context.Background(), "BEGIN EXCLUSIVE;"); err != nil {
+		tb.Fatalf("Failed to lock test database: %v", err)
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			conn.ExecContext(context.Background(), "ROLLBACK;")
+			conn.Close()
+			hDB.Close()
+		})
+	}
+}
@@ -0,0 +1,772 @@
+package gpbckpexporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+// The loader reads data from history database with a few set-based queries
+// and assembles backup configs in memory.
+// It's much faster than reading data for each backup separately
+// via gpbckpconfig.GetBackupDataDB, especially for large history databases.
+// Backups from legacy YAML history file are read via the same queries.
+
+const backupsQuery = `
+SELECT b.timestamp, b.backup_dir, b.backup_version, b.compressed, b.compression_type,
+	b.database_name, b.database_version, b.data_only, b.date_deleted,
+	b.exclude_schema_filtered, b.exclude_table_filtered, b.include_schema_filtered,
+	b.include_table_filtered, b.incremental, b.leaf_partition_data, b.metadata_only,
+	b.plugin, b.plugin_version, b.single_data_file, b.end_time, b.without_globals,
+	b.with_statistics, b.status
+FROM backups b
+%s
+ORDER BY b.timestamp DESC;`
+
+const databasesQuery = `
+SELECT DISTINCT b.database_name
+FROM backups b
+%s
+ORDER BY b.database_name;`
+
+const auxTableQuery = `
+SELECT a.timestamp, a.name
+FROM %s a
+JOIN backups b ON a.timestamp = b.timestamp
+%s;`
+
+const restorePlansQuery = `
+SELECT DISTINCT r.timestamp, r.restore_plan_timestamp
+FROM restore_plans r
+JOIN backups b ON r.timestamp = b.timestamp
+%s
+ORDER BY r.timestamp, r.restore_plan_timestamp;`
+
+const restorePlanTablesQuery = `
+SELECT t.timestamp, t.restore_plan_timestamp, t.table_fqn
+FROM restore_plan_tables t
+JOIN backups b ON t.timestamp = b.timestamp
+%s;`
+
+const restorePlanStatesQuery = `
+SELECT p.timestamp, p.status, p.end_time, p.date_deleted
+FROM backups p
+WHERE p.timestamp IN (
+	SELECT r.restore_plan_timestamp
+	FROM restore_plans r
+	JOIN backups b ON r.timestamp = b.timestamp
+	%s
+);`
+
+const dependentsQuery = `
+SELECT r.restore_plan_timestamp, COUNT(DISTINCT r.timestamp)
+FROM restore_plans r
+WHERE r.timestamp != r.restore_plan_timestamp
+GROUP BY r.restore_plan_timestamp;`
+
+const pendingDeletionsQuery = `
+SELECT b.timestamp, b.date_deleted
+FROM backups b
+WHERE b.date_deleted IN (?, ?, ?);`
+
+const lastBackupsQuery = `
+SELECT b.database_name, b.incremental, b.data_only, b.metadata_only, MAX(b.end_time)
+FROM backups b
+%s
+GROUP BY b.database_name, b.incremental, b.data_only, b.metadata_only;`
+
+// Backups from restore plan must be present in history database, successful and not deleted.
+const recoveryPointsQuery = `
+SELECT b.database_name, MAX(b.timestamp)
+FROM backups b
+%s
+	AND NOT EXISTS (
+		SELECT 1
+		FROM restore_plans r
+		LEFT JOIN backups p ON p.timestamp = r.restore_plan_timestamp
+		WHERE r.timestamp = b.timestamp
+			AND (p.timestamp IS NULL OR p.status != ? OR p.date_deleted NOT IN ('', ?, ?))
+	)
+GROUP BY b.database_name;`
+
+const incrementalChainsQuery = `
+SELECT DISTINCT b.database_name, b.incremental, COALESCE(r.restore_plan_timestamp, '')
+FROM backups b
+LEFT JOIN restore_plans r ON r.timestamp = b.timestamp
+WHERE b.timestamp IN (
+	SELECT MAX(b.timestamp)
+	FROM backups b
+	%s
+	GROUP BY b.database_name
+);`
+
+const filterStatsQuery = `
+SELECT %s
+FROM backups b;`
+
+// Filter names for filter statistics.
+const (
+	filterFailed     = "failed"
+	filterDeleted    = "deleted"
+	filterDatabase   = "database"
+	filterBackupType = "backup_type"
+	filterDepth      = "depth"
+)
+
+// Statistics and auxiliary data of reading backups from history file.
+type historyStats struct {
+	// Number of rows read from backups table in history database.
+	// With cache only mutable fields are read for known non-terminal backups.
+	rowsRead int
+	// Number of backups filtered out by each filter separately.
+	// The same backup can be filtered out by several filters.
+	rowsFiltered map[string]int
+	// Copy of history file, which is read in snapshot copy mode.
+	snapshot *historySnapshot
+	// States of backups from restore plans of selected backups by timestamp,
+	// regardless of filters. Nil, if states can't be read.
+	planStates map[string]backupState
+	// Number of backups, which depend on each backup, regardless of filters.
+	dependents dependentsMap
+	// Backups in non-terminal deletion states, regardless of filters.
+	pendingDeletions pendingDeletionMap
+	// End time of the last successful backup by database and backup type,
+	// regardless of collection depth. Nil, if it can't be read.
+	lastBackups lastBackupMap
+	// Start time of the newest recovery point by database,
+	// regardless of backup type filter and collection depth. Nil, if it can't be read.
+	recoveryPoints recoveryPointMap
+	// Length of the current incremental chain by database,
+	// regardless of backup type filter and collection depth. Nil, if it can't be read.
+	incrementalChains map[string]int
+}
+
+// Filters for backups, which are applied on history database side.
+type backupFilter struct {
+	collectDeleted bool
+	collectFailed  bool
+	backupType     string
+	dbInclude      []string
+	dbExclude      []string
+	// Only backups with timestamp after this value are selected.
+	// Empty value - without limit.
+	timestampAfter string
+	// Only backups with these timestamps are selected.
+	// Empty list - without limit.
+	timestamps []string
+}
+
+// Get WHERE clause and its arguments for backups selection.
+// If the same database is specified in include and exclude lists,
+// backups for this database are still selected, because GetGPBackupInfo
+// needs them to warn about such database and set exporter status.
+func (f backupFilter) whereClause() (string, []any) {
+	return f.buildWhereClause(true)
+}
+
+// Get WHERE clause and its arguments for databases selection.
+// Only filters for deleted and failed backups and for databases are used,
+// because GetGPBackupInfo sets exporter status for all such databases.
+func (f backupFilter) databasesWhereClause() (string, []any) {
+	return f.buildWhereClause(false)
+}
+
+func (f backupFilter) buildWhereClause(withBackupFilters bool) (string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+	addCondition := func(condition string, conditionArgs []any) {
+		if condition != "" {
+			conditions = append(conditions, condition)
+			args = append(args, conditionArgs...)
+		}
+	}
+	addCondition(f.failedCondition())
+	addCondition(f.deletedCondition())
+	if withBackupFilters {
+		addCondition(f.depthCondition())
+		addCondition(f.timestampsCondition())
+	}
+	addCondition(f.databaseCondition(withBackupFilters))
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// Get SQL condition for failed backups filter.
+// Together with deleted backups filter it selects:
+//   - all backups (active, deleted, failed);
+//   - only active and deleted backups, failed - hidden;
+//   - only active and failed backups, deleted - hidden;
+//   - only active backups or backups with deletion status "In progress", deleted and failed - hidden.
+func (f backupFilter) failedCondition() (string, []any) {
+	if f.collectFailed {
+		return "", nil
+	}
+	return "b.status != ?", []any{gpbckpconfig.BackupStatusFailure}
+}
+
+// Get SQL condition for deleted backups filter.
+func (f backupFilter) deletedCondition() (string, []any) {
+	if f.collectDeleted {
+		return "", nil
+	}
+	return "b.date_deleted IN ('', ?, ?, ?)", []any{gpbckpconfig.DateDeletedInProgress, gpbckpconfig.DateDeletedPluginFailed, gpbckpconfig.DateDeletedLocalFailed}
+}
+
+// Get SQL condition for collection depth filter.
+func (f backupFilter) depthCondition() (string, []any) {
+	if f.timestampAfter == "" {
+		return "", nil
+	}
+	return "b.timestamp > ?", []any{f.timestampAfter}
+}
+
+// Get SQL condition for selecting backups with specific timestamps.
+func (f backupFilter) timestampsCondition() (string, []any) {
+	if len(f.timestamps) == 0 {
+		return "", nil
+	}
+	args := make([]any, 0, len(f.timestamps))
+	for _, timestamp := range f.timestamps {
+		args = append(args, timestamp)
+	}
+	return fmt.Sprintf("b.timestamp IN (%s)", placeholders(len(f.timestamps))), args
+}
+
+// Get SQL condition for databases filter and, if withBackupType is true, for backup type filter.
+func (f backupFilter) databaseCondition(withBackupType bool) (string, []any) {
+	var (
+		dbConditions []string
+		dbArgs       []any
+	)
+	dbInclude := nonEmptyValues(f.dbInclude)
+	dbExclude := nonEmptyValues(f.dbExclude)
+	if len(dbInclude) != 0 {
+		dbConditions = append(dbConditions, fmt.Sprintf("b.database_name IN (%s)", placeholders(len(dbInclude))))
+		dbArgs = append(dbArgs, dbInclude...)
+	}
+	if len(dbExclude) != 0 {
+		dbConditions = append(dbConditions, fmt.Sprintf("b.database_name NOT IN (%s)", placeholders(len(dbExclude))))
+		dbArgs = append(dbArgs, dbExclude...)
+	}
+	if typeCondition := backupTypeCondition(f.backupType); withBackupType && typeCondition != "" {
+		dbConditions = append(dbConditions, typeCondition)
+	}
+	if len(dbConditions) == 0 {
+		return "", nil
+	}
+	dbCondition := strings.Join(dbConditions, " AND ")
+	// Databases specified in include and exclude lists.
+	var dbConflict []any
+	for _, db := range f.dbExclude {
+		if db != "" && dbInList(db, f.dbInclude) {
+			dbConflict = append(dbConflict, db)
+		}
+	}
+	if len(dbConflict) != 0 {
+		dbCondition = fmt.Sprintf("b.database_name IN (%s) OR (%s)", placeholders(len(dbConflict)), dbCondition)
+		dbArgs = append(dbConflict, dbArgs...)
+	}
+	return "(" + dbCondition + ")", dbArgs
+}
+
+// Get SQL condition for backup type.
+// For unknown backup type empty condition is returned,
+// such backups are filtered out in GetGPBackupInfo.
+func backupTypeCondition(backupType string) string {
+	switch backupType {
+	case gpbckpconfig.BackupTypeFull:
+		return "(b.incremental = 0 AND b.data_only = 0 AND b.metadata_only = 0)"
+	case gpbckpconfig.BackupTypeIncremental:
+		return "(b.incremental = 1 AND b.data_only = 0 AND b.metadata_only = 0)"
+	case gpbckpconfig.BackupTypeDataOnly:
+		return "(b.data_only = 1 AND b.incremental = 0 AND b.metadata_only = 0)"
+	case gpbckpconfig.BackupTypeMetadataOnly:
+		return "(b.metadata_only = 1 AND (b.incremental = 0 OR b.data_only = 0))"
+	default:
+		return ""
+	}
+}
+
+// Get list values without empty strings.
+func nonEmptyValues(list []string) []any {
+	values := make([]any, 0, len(list))
+	for _, val := range list {
+		if val != "" {
+			values = append(values, val)
+		}
+	}
+	return values
+}
+
+// Get comma separated list of placeholders for SQL query.
+func placeholders(count int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", count), ", ")
+}
+
+// SQL condition for specific filter.
+type filterCondition struct {
+	name      string
+	condition string
+	args      []any
+}
+
+// Get SQL conditions for each filter.
+// Filters, which are not set, have empty conditions.
+func (f backupFilter) filterConditions() []filterCondition {
+	failed, failedArgs := f.failedCondition()
+	deleted, deletedArgs := f.deletedCondition()
+	database, databaseArgs := f.databaseCondition(false)
+	depth, depthArgs := f.depthCondition()
+	return []filterCondition{
+		{filterFailed, failed, failedArgs},
+		{filterDeleted, deleted, deletedArgs},
+		{filterDatabase, database, databaseArgs},
+		{filterBackupType, backupTypeCondition(f.backupType), nil},
+		{filterDepth, depth, depthArgs},
+	}
+}
+
+// Load number of backups filtered out by each filter.
+func loadFilterStatsDB(ctx context.Context, hDB *sql.DB, filter backupFilter) (historyStats, error) {
+	var (
+		columns []string
+		args    []any
+		names   []string
+	)
+	stats := historyStats{rowsFiltered: make(map[string]int)}
+	for _, fc := range filter.filterConditions() {
+		stats.rowsFiltered[fc.name] = 0
+		if fc.condition == "" {
+			continue
+		}
+		columns = append(columns, fmt.Sprintf("COALESCE(SUM(CASE WHEN %s THEN 0 ELSE 1 END), 0)", fc.condition))
+		args = append(args, fc.args...)
+		names = append(names, fc.name)
+	}
+	// Without filters nothing is filtered out.
+	if len(names) == 0 {
+		return stats, nil
+	}
+	filtered := make([]int, len(names))
+	dest := make([]any, 0, len(filtered))
+	for i := range filtered {
+		dest = append(dest, &filtered[i])
+	}
+	if err := hDB.QueryRowContext(ctx, fmt.Sprintf(filterStatsQuery, strings.Join(columns, ",\n\t")), args...).Scan(dest...); err != nil {
+		return historyStats{}, err
+	}
+	for i, name := range names {
+		stats.rowsFiltered[name] = filtered[i]
+	}
+	return stats, nil
+}
+
+// Load states of backups from restore plans of selected backups.
+// Backups from restore plans are loaded regardless of filters,
+// because deleted or failed backup breaks restore chain.
+func loadRestorePlanStatesDB(ctx context.Context, hDB *sql.DB, filter backupFilter) (map[string]backupState, error) {
+	where, args := filter.whereClause()
+	rows, err := hDB.QueryContext(ctx, fmt.Sprintf(restorePlanStatesQuery, where), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	states := make(map[string]backupState)
+	for rows.Next() {
+		var state backupState
+		if err := rows.Scan(&state.timestamp, &state.status, &state.endTime, &state.dateDeleted); err != nil {
+			return nil, newRowDecodeError(err)
+		}
+		states[state.timestamp] = state
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+// Load number of later backups, which depend on each backup.
+// All backups from history database are taken into account, regardless of filters.
+func loadDependentsDB(ctx context.Context, hDB *sql.DB) (dependentsMap, error) {
+	rows, err := hDB.QueryContext(ctx, dependentsQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	dependents := make(dependentsMap)
+	for rows.Next() {
+		var (
+			timestamp string
+			count     int
+		)
+		if err := rows.Scan(&timestamp, &count); err != nil {
+			return nil, newRowDecodeError(err)
+		}
+		dependents[timestamp] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return dependents, nil
+}
+
+// Load backups in non-terminal deletion states.
+// All backups from history database are taken into account, regardless of filters,
+// so deletion age isn't reset, when filters are changed.
+func loadPendingDeletionsDB(ctx context.Context, hDB *sql.DB) (pendingDeletionMap, error) {
+	rows, err := hDB.QueryContext(ctx, pendingDeletionsQuery, gpbckpconfig.DateDeletedInProgress, gpbckpconfig.DateDeletedPluginFailed, gpbckpconfig.DateDeletedLocalFailed)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	pending := make(pendingDeletionMap)
+	for rows.Next() {
+		var timestamp, dateDeleted string
+		if err := rows.Scan(&timestamp, &dateDeleted); err != nil {
+			return nil, newRowDecodeError(err)
+		}
+		pending[timestamp] = dateDeleted
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// Load end time of the last successful backup for each database and backup type.
+// Collection depth isn't applied, so databases with backups older than collection depth
+// aren't reported as never backed up.
+func loadLastBackupsDB(ctx context.Context, hDB *sql.DB, filter backupFilter) (lastBackupMap, error) {
+	filter.timestampAfter = ""
+	filter.timestamps = nil
+	where, args := filter.whereClause()
+	where = addWhereCondition(where, "b.status = ?")
+	args = append(args, gpbckpconfig.BackupStatusSuccess)
+	rows, err := hDB.QueryContext(ctx, fmt.Sprintf(lastBackupsQuery, where), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	lastBackups := make(lastBackupMap)
+	for rows.Next() {
+		var (
+			backupConfig   gpbckpconfig.BackupConfig
+			isIncremental  int
+			isDataOnly     int
+			isMetadataOnly int
+		)
+		if err := rows.Scan(&backupConfig.DatabaseName, &isIncremental, &isDataOnly, &isMetadataOnly, &backupConfig.EndTime); err != nil {
+			return nil, newRowDecodeError(err)
+		}
+		backupConfig.Incremental = isIncremental == 1
+		backupConfig.DataOnly = isDataOnly == 1
+		backupConfig.MetadataOnly = isMetadataOnly == 1
+		if err := addLastBackup(lastBackups, backupConfig); err != nil {
+			return nil, newRowDecodeError(err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return lastBackups, nil
+}
+
+// Load start time of the newest recovery point for each database.
+// Recovery point is successful and not deleted backup with user data,
+// all backups from its restore plan are successful and not deleted too.
+// Backup type filter and collection depth aren't applied,
+// so any newer backup with user data and old backups are taken into account.
+func loadRecoveryPointsDB(ctx context.Context, hDB *sql.DB, filter backupFilter) (recoveryPointMap, error) {
+	filter.backupType = ""
+	filter.timestampAfter = ""
+	filter.timestamps = nil
+	where, args := filter.whereClause()
+	where = addWhereCondition(where, "b.status = ? AND b.date_deleted IN ('', ?, ?) AND NOT "+backupTypeCondition(gpbckpconfig.BackupTypeMetadataOnly))
+	activeArgs := []any{gpbckpconfig.BackupStatusSuccess, gpbckpconfig.DateDeletedPluginFailed, gpbckpconfig.DateDeletedLocalFailed}
+	args = append(append(args, activeArgs...), activeArgs...)
+	rows, err := hDB.QueryContext(ctx, fmt.Sprintf(recoveryPointsQuery, where), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	recoveryPoints := make(recoveryPointMap)
+	for rows.Next() {
+		var db, timestamp string
+		if err := rows.Scan(&db, &timestamp); err != nil {
+			return nil, newRowDecodeError(err)
+		}
+		// See the note about timezone in collectBackupInfo.
+		startTime, err := time.ParseInLocation(gpbckpconfig.Layout, timestamp, time.Local)
+		if err != nil {
+			return nil, newRowDecodeError(err)
+		}
+		recoveryPoints[db] = startTime
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return recoveryPoints, nil
+}
+
+// Load length of the current incremental chain for each database.
+// The current incremental chain is defined by the last successful full or incremental backup.
+// Backup type filter and collection depth aren't applied,
+// so chain isn't cut off by them.
+func loadIncrementalChainsDB(ctx context.Context, hDB *sql.DB, filter backupFilter) (map[string]int, error) {
+	filter.backupType = ""
+	filter.timestampAfter = ""
+	filter.timestamps = nil
+	where, args := filter.whereClause()
+	where = addWhereCondition(where, fmt.Sprintf("b.status = ? AND (%s OR %s)",
+		backupTypeCondition(gpbckpconfig.BackupTypeFull), backupTypeCondition(gpbckpconfig.BackupTypeIncremental)))
+	args = append(args, gpbckpconfig.BackupStatusSuccess)
+	rows, err := hDB.QueryContext(ctx, fmt.Sprintf(incrementalChainsQuery, where), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	// The last backup with its restore plan by database.
+	lastBackups := make(map[string]*gpbckpconfig.BackupConfig)
+	for rows.Next() {
+		var (
+			db                   string
+			isIncremental        int
+			restorePlanTimestamp string
+		)
+		if err := rows.Scan(&db, &isIncremental, &restorePlanTimestamp); err != nil {
+			return nil, newRowDecodeError(err)
+		}
+		backupData, ok := lastBackups[db]
+		if !ok {
+			backupData = &gpbckpconfig.BackupConfig{Incremental: isIncremental == 1}
+			lastBackups[db] = backupData
+		}
+		if restorePlanTimestamp != "" {
+			backupData.RestorePlan = append(backupData.RestorePlan, gpbckpconfig.RestorePlanEntry{Timestamp: restorePlanTimestamp})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	incrementalChains := make(map[string]int, len(lastBackups))
+	for db, backupData := range lastBackups {
+		incrementalChains[db] = getIncrementalChainLength(*backupData)
+	}
+	return incrementalChains, nil
+}
+
+// Add condition to WHERE clause.
+func addWhereCondition(where, condition string) string {
+	if where == "" {
+		return "WHERE " + condition
+	}
+	return where + " AND " + condition
+}
+
+// Load backup configs from history database.
+// Backup configs are sorted by timestamp in descending order.
+func loadBackupConfigsDB(ctx context.Context, hDB *sql.DB, filter backupFilter) ([]gpbckpconfig.BackupConfig, error) {
+	where, args := filter.whereClause()
+	backupConfigs, err := loadBackups(ctx, hDB, where, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(backupConfigs) == 0 {
+		return backupConfigs, nil
+	}
+	// Position of backup config in result list by timestamp.
+	backupIndex := make(map[string]int, len(backupConfigs))
+	for i := range backupConfigs {
+		backupIndex[backupConfigs[i].Timestamp] = i
+	}
+	if err := loadAuxTables(ctx, hDB, where, args, backupConfigs, backupIndex); err != nil {
+		return nil, err
+	}
+	if err := loadRestorePlans(ctx, hDB, where, args, backupConfigs, backupIndex); err != nil {
+		return nil, err
+	}
+	return backupConfigs, nil
+}
+
+// Load names of databases, which have backups in history database.
+func loadDatabaseNamesDB(ctx context.Context, hDB *sql.DB, filter backupFilter) ([]string, error) {
+	where, args := filter.databasesWhereClause()
+	rows, err := hDB.QueryContext(ctx, fmt.Sprintf(databasesQuery, where), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	dbNames := make([]string, 0)
+	for rows.Next() {
+		var dbName string
+		if err := rows.Scan(&dbName); err != nil {
+			return nil, newRowDecodeError(err)
+		}
+		dbNames = append(dbNames, dbName)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return dbNames, nil
+}
+
+func loadBackups(ctx context.Context, hDB *sql.DB, where string, args []any) ([]gpbckpconfig.BackupConfig, error) {
+	rows, err := hDB.QueryContext(ctx, fmt.Sprintf(backupsQuery, where), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	backupConfigs := make([]gpbckpconfig.BackupConfig, 0)
+	for rows.Next() {
+		var (
+			backupConfig         gpbckpconfig.BackupConfig
+			isCompressed         int
+			isDataOnly           int
+			isExclSchemaFiltered int
+			isExclTableFiltered  int
+			isInclSchemaFiltered int
+			isInclTableFiltered  int
+			isIncremental        int
+			isLeafPartition      int
+			isMetadataOnly       int
+			isSingleDataFile     int
+			isWithoutGlobals     int
+			isWithStatistics     int
+		)
+		err := rows.Scan(
+			&backupConfig.Timestamp, &backupConfig.BackupDir, &backupConfig.BackupVersion,
+			&isCompressed, &backupConfig.CompressionType, &backupConfig.DatabaseName,
+			&backupConfig.DatabaseVersion, &isDataOnly, &backupConfig.DateDeleted,
+			&isExclSchemaFiltered, &isExclTableFiltered, &isInclSchemaFiltered,
+			&isInclTableFiltered, &isIncremental, &isLeafPartition, &isMetadataOnly,
+			&backupConfig.Plugin, &backupConfig.PluginVersion, &isSingleDataFile,
+			&backupConfig.EndTime, &isWithoutGlobals, &isWithStatistics, &backupConfig.Status,
+		)
+		if err != nil {
+			return nil, newRowDecodeError(err)
+		}
+		backupConfig.Compressed = isCompressed == 1
+		backupConfig.DataOnly = isDataOnly == 1
+		backupConfig.ExcludeSchemaFiltered = isExclSchemaFiltered == 1
+		backupConfig.ExcludeTableFiltered = isExclTableFiltered == 1
+		backupConfig.IncludeSchemaFiltered = isInclSchemaFiltered == 1
+		backupConfig.IncludeTableFiltered = isInclTableFiltered == 1
+		backupConfig.Incremental = isIncremental == 1
+		backupConfig.LeafPartitionData = isLeafPartition == 1
+		backupConfig.MetadataOnly = isMetadataOnly == 1
+		backupConfig.SingleDataFile = isSingleDataFile == 1
+		backupConfig.WithoutGlobals = isWithoutGlobals == 1
+		backupConfig.WithStatistics = isWithStatistics == 1
+		// Lists are never nil.
+		backupConfig.ExcludeRelations = make([]string, 0)
+		backupConfig.ExcludeSchemas = make([]string, 0)
+		backupConfig.IncludeRelations = make([]string, 0)
+		backupConfig.IncludeSchemas = make([]string, 0)
+		backupConfig.RestorePlan = make([]gpbckpconfig.RestorePlanEntry, 0)
+		backupConfigs = append(backupConfigs, backupConfig)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return backupConfigs, nil
+}
+
+func loadAuxTables(ctx context.Context, hDB *sql.DB, where string, args []any, backupConfigs []gpbckpconfig.BackupConfig, backupIndex map[string]int) error {
+	auxTables := []struct {
+		name  string
+		field func(*gpbckpconfig.BackupConfig) *[]string
+	}{
+		{"exclude_relations", func(b *gpbckpconfig.BackupConfig) *[]string { return &b.ExcludeRelations }},
+		{"exclude_schemas", func(b *gpbckpconfig.BackupConfig) *[]string { return &b.ExcludeSchemas }},
+		{"include_relations", func(b *gpbckpconfig.BackupConfig) *[]string { return &b.IncludeRelations }},
+		{"include_schemas", func(b *gpbckpconfig.BackupConfig) *[]string { return &b.IncludeSchemas }},
+	}
+	for _, auxTable := range auxTables {
+		err := func() error {
+			rows, err := hDB.QueryContext(ctx, fmt.Sprintf(auxTableQuery, auxTable.name, where), args...)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+			for rows.Next() {
+				var timestamp, name string
+				if err := rows.Scan(&timestamp, &name); err != nil {
+					return newRowDecodeError(err)
+				}
+				if i, ok := backupIndex[timestamp]; ok {
+					field := auxTable.field(&backupConfigs[i])
+					*field = append(*field, name)
+				}
+			}
+			return rows.Err()
+		}()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadRestorePlans(ctx context.Context, hDB *sql.DB, where string, args []any, backupConfigs []gpbckpconfig.BackupConfig, backupIndex map[string]int) error {
+	// Position of restore plan entry in backup restore plan
+	// by backup timestamp and restore plan timestamp.
+	type restorePlanKey struct {
+		timestamp            string
+		restorePlanTimestamp string
+	}
+	restorePlanIndex := make(map[restorePlanKey]int)
+	rows, err := hDB.QueryContext(ctx, fmt.Sprintf(restorePlansQuery, where), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key restorePlanKey
+		if err := rows.Scan(&key.timestamp, &key.restorePlanTimestamp); err != nil {
+			return newRowDecodeError(err)
+		}
+		if i, ok := backupIndex[key.timestamp]; ok {
+			restorePlanIndex[key] = len(backupConfigs[i].RestorePlan)
+			backupConfigs[i].RestorePlan = append(
+				backupConfigs[i].RestorePlan,
+				gpbckpconfig.RestorePlanEntry{
+					Timestamp: key.restorePlanTimestamp,
+					TableFQNs: make([]string, 0),
+				},
+			)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	tableRows, err := hDB.QueryContext(ctx, fmt.Sprintf(restorePlanTablesQuery, where), args...)
+	if err != nil {
+		return err
+	}
+	defer tableRows.Close()
+	for tableRows.Next() {
+		var (
+			key      restorePlanKey
+			tableFQN string
+		)
+		if err := tableRows.Scan(&key.timestamp, &key.restorePlanTimestamp, &tableFQN); err != nil {
+			return newRowDecodeError(err)
+		}
+		i, ok := backupIndex[key.timestamp]
+		if !ok {
+			continue
+		}
+		if j, ok := restorePlanIndex[key]; ok {
+			backupConfigs[i].RestorePlan[j].TableFQNs = append(backupConfigs[i].RestorePlan[j].TableFQNs, tableFQN)
+		}
+	}
+	return tableRows.Err()
+}
@@ -0,0 +1,691 @@
+package gpbckpexporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/greenplum-db/gpbackup/history"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+func TestLoadBackupConfigsDB(t *testing.T) {
+	fullBackup := templateBackupConfig()
+	fullBackup.IncludeSchemas = []string{"sales", "public"}
+	fullBackup.IncludeSchemaFiltered = true
+	incrBackup := templateBackupConfig()
+	incrBackup.Timestamp = "20230118162654"
+	incrBackup.EndTime = "20230118162656"
+	incrBackup.Incremental = true
+	incrBackup.ExcludeRelations = []string{"public.t1"}
+	incrBackup.ExcludeTableFiltered = true
+	incrBackup.RestorePlan = []gpbckpconfig.RestorePlanEntry{
+		{Timestamp: "20230118152654", TableFQNs: []string{"public.t2", "public.t3"}},
+		{Timestamp: "20230118162654", TableFQNs: []string{"public.t4"}},
+	}
+	failedBackup := templateBackupConfig()
+	failedBackup.Timestamp = "20230118172654"
+	failedBackup.EndTime = "20230118172656"
+	failedBackup.Status = gpbckpconfig.BackupStatusFailure
+	deletedBackup := templateBackupConfig()
+	deletedBackup.Timestamp = "20230118182654"
+	deletedBackup.EndTime = "20230118182656"
+	deletedBackup.DateDeleted = "20230118192654"
+	deletingBackup := templateBackupConfig()
+	deletingBackup.Timestamp = "20230118192654"
+	deletingBackup.EndTime = "20230118192656"
+	deletingBackup.DateDeleted = gpbckpconfig.DateDeletedInProgress
+	historyFile := fakeHistoryFileBackups(t, fullBackup, incrBackup, failedBackup, deletedBackup, deletingBackup)
+	defer os.Remove(historyFile)
+	hDB, err := gpbckpconfig.OpenHistoryDB(historyFile)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer hDB.Close()
+	tests := []struct {
+		name           string
+		collectDeleted bool
+		collectFailed  bool
+		wantCount      int
+	}{
+		{"OnlyActive", false, false, 3},
+		{"ActiveAndDeleted", true, false, 4},
+		{"ActiveAndFailed", false, true, 4},
+		{"All", true, true, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadBackupConfigsDB(context.Background(), hDB, backupFilter{collectDeleted: tt.collectDeleted, collectFailed: tt.collectFailed})
+			if err != nil {
+				t.Fatalf("\nGet error during load backups:\n%v", err)
+			}
+			want := getBackupConfigsPerBackupDB(t, hDB, tt.collectDeleted, tt.collectFailed)
+			if len(got) != tt.wantCount {
+				t.Errorf("\nVariables do not match:\n%d\nwant:\n%d", len(got), tt.wantCount)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, want)
+			}
+		})
+	}
+}
+
+func TestLoadBackupConfigsDBFilters(t *testing.T) {
+	fullBackup := templateBackupConfig()
+	incrBackup := templateBackupConfig()
+	incrBackup.Timestamp = "20230119152654"
+	incrBackup.EndTime = "20230119152656"
+	incrBackup.Incremental = true
+	dataOnlyBackup := templateBackupConfig()
+	dataOnlyBackup.Timestamp = "20230120152654"
+	dataOnlyBackup.EndTime = "20230120152656"
+	dataOnlyBackup.DatabaseName = "demo"
+	dataOnlyBackup.DataOnly = true
+	metadataOnlyBackup := templateBackupConfig()
+	metadataOnlyBackup.Timestamp = "20230121152654"
+	metadataOnlyBackup.EndTime = "20230121152656"
+	metadataOnlyBackup.DatabaseName = "demo"
+	metadataOnlyBackup.MetadataOnly = true
+	metadataOnlyBackup.Incremental = true
+	otherBackup := templateBackupConfig()
+	otherBackup.Timestamp = "20230122152654"
+	otherBackup.EndTime = "20230122152656"
+	otherBackup.DatabaseName = "other"
+	historyFile := fakeHistoryFileBackups(t, fullBackup, incrBackup, dataOnlyBackup, metadataOnlyBackup, otherBackup)
+	defer os.Remove(historyFile)
+	hDB, err := gpbckpconfig.OpenHistoryDB(historyFile)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer hDB.Close()
+	tests := []struct {
+		name   string
+		filter backupFilter
+		want   []string
+	}{
+		{
+			"EmptyFilters",
+			backupFilter{dbInclude: []string{""}, dbExclude: []string{""}},
+			[]string{"20230122152654", "20230121152654", "20230120152654", "20230119152654", "20230118152654"},
+		},
+		{
+			"DBInclude",
+			backupFilter{dbInclude: []string{"test", "other"}},
+			[]string{"20230122152654", "20230119152654", "20230118152654"},
+		},
+		{
+			"DBExclude",
+			backupFilter{dbExclude: []string{"test"}},
+			[]string{"20230122152654", "20230121152654", "20230120152654"},
+		},
+		{
+			"DBIncludeAndExclude",
+			backupFilter{dbInclude: []string{"test", "demo"}, dbExclude: []string{"test"}, backupType: "full"},
+			[]string{"20230119152654", "20230118152654"},
+		},
+		{
+			"BackupTypeFull",
+			backupFilter{backupType: "full"},
+			[]string{"20230122152654", "20230118152654"},
+		},
+		{
+			"BackupTypeIncremental",
+			backupFilter{backupType: "incremental"},
+			[]string{"20230119152654"},
+		},
+		{
+			"BackupTypeDataOnly",
+			backupFilter{backupType: "data-only"},
+			[]string{"20230120152654"},
+		},
+		{
+			"BackupTypeMetadataOnly",
+			backupFilter{backupType: "metadata-only"},
+			[]string{"20230121152654"},
+		},
+		{
+			"TimestampAfter",
+			backupFilter{timestampAfter: "20230120152654"},
+			[]string{"20230122152654", "20230121152654"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backupConfigs, err := loadBackupConfigsDB(context.Background(), hDB, tt.filter)
+			if err != nil {
+				t.Fatalf("\nGet error during load backups:\n%v", err)
+			}
+			got := make([]string, 0, len(backupConfigs))
+			for _, backupConfig := range backupConfigs {
+				got = append(got, backupConfig.Timestamp)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadDatabaseNamesDB(t *testing.T) {
+	fullBackup := templateBackupConfig()
+	demoBackup := templateBackupConfig()
+	demoBackup.Timestamp = "20230119152654"
+	demoBackup.EndTime = "20230119152656"
+	demoBackup.DatabaseName = "demo"
+	demoBackup.Incremental = true
+	historyFile := fakeHistoryFileBackups(t, fullBackup, demoBackup)
+	defer os.Remove(historyFile)
+	hDB, err := gpbckpconfig.OpenHistoryDB(historyFile)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer hDB.Close()
+	tests := []struct {
+		name   string
+		filter backupFilter
+		want   []string
+	}{
+		{
+			"BackupFiltersIgnored",
+			backupFilter{backupType: "full", timestampAfter: "20230120152654"},
+			[]string{"demo", "test"},
+		},
+		{
+			"DBExclude",
+			backupFilter{dbExclude: []string{"test"}},
+			[]string{"demo"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadDatabaseNamesDB(context.Background(), hDB, tt.filter)
+			if err != nil {
+				t.Fatalf("\nGet error during load databases:\n%v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackupFilterWhereClause(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    backupFilter
+		wantWhere string
+		wantArgs  []any
+	}{
+		{
+			"AllBackups",
+			backupFilter{collectDeleted: true, collectFailed: true},
+			"",
+			nil,
+		},
+		{
+			"ActiveBackups",
+			backupFilter{},
+			"WHERE b.status != ? AND b.date_deleted IN ('', ?, ?, ?)",
+			[]any{"Failure", "In progress", "Plugin Backup Delete Failed", "Local Delete Failed"},
+		},
+		{
+			"AllFilters",
+			backupFilter{
+				collectDeleted: true,
+				collectFailed:  true,
+				backupType:     "full",
+				dbInclude:      []string{"test", "demo"},
+				dbExclude:      []string{"demo", ""},
+				timestampAfter: "20230118152654",
+			},
+			"WHERE b.timestamp > ? AND (b.database_name IN (?) OR (b.database_name IN (?, ?) AND b.database_name NOT IN (?) AND " +
+				"(b.incremental = 0 AND b.data_only = 0 AND b.metadata_only = 0)))",
+			[]any{"20230118152654", "demo", "test", "demo", "demo"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotWhere, gotArgs := tt.filter.whereClause()
+			if gotWhere != tt.wantWhere {
+				t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", gotWhere, tt.wantWhere)
+			}
+			if !reflect.DeepEqual(gotArgs, tt.wantArgs) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", gotArgs, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestLoadFilterStatsDB(t *testing.T) {
+	fullBackup := templateBackupConfig()
+	failedBackup := templateBackupConfig()
+	failedBackup.Timestamp = "20230119152654"
+	failedBackup.EndTime = "20230119152656"
+	failedBackup.Status = gpbckpconfig.BackupStatusFailure
+	demoBackup := templateBackupConfig()
+	demoBackup.Timestamp = "20230120152654"
+	demoBackup.EndTime = "20230120152656"
+	demoBackup.DatabaseName = "demo"
+	demoBackup.Incremental = true
+	historyFile := fakeHistoryFileBackups(t, fullBackup, failedBackup, demoBackup)
+	defer os.Remove(historyFile)
+	hDB, err := gpbckpconfig.OpenHistoryDB(historyFile)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer hDB.Close()
+	tests := []struct {
+		name   string
+		filter backupFilter
+		want   historyStats
+	}{
+		{
+			"WithoutFilters",
+			backupFilter{collectDeleted: true, collectFailed: true},
+			historyStats{rowsFiltered: map[string]int{"failed": 0, "deleted": 0, "database": 0, "backup_type": 0, "depth": 0}},
+		},
+		{
+			"AllFilters",
+			backupFilter{backupType: "full", dbExclude: []string{"demo"}, timestampAfter: "20230118152654"},
+			historyStats{rowsFiltered: map[string]int{"failed": 1, "deleted": 0, "database": 1, "backup_type": 1, "depth": 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadFilterStatsDB(context.Background(), hDB, tt.filter)
+			if err != nil {
+				t.Fatalf("\nGet error during load filter stats:\n%v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadDependentsDB(t *testing.T) {
+	chain := templateIncrementalChain()
+	historyFile := fakeHistoryFileBackups(t, chain...)
+	defer os.Remove(historyFile)
+	hDB, err := gpbckpconfig.OpenHistoryDB(historyFile)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer hDB.Close()
+	got, err := loadDependentsDB(context.Background(), hDB)
+	if err != nil {
+		t.Fatalf("\nGet error during load dependents:\n%v", err)
+	}
+	want := dependentsMap{
+		"20230118150000": 2,
+		"20230118160000": 1,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestLoadPendingDeletionsDB(t *testing.T) {
+	backups := make([]gpbckpconfig.BackupConfig, 0, 4)
+	for _, value := range []struct{ timestamp, dateDeleted, status string }{
+		{"20230118152654", gpbckpconfig.DateDeletedInProgress, gpbckpconfig.BackupStatusSuccess},
+		{"20230118162654", gpbckpconfig.DateDeletedLocalFailed, gpbckpconfig.BackupStatusFailure},
+		{"20230118172654", "", gpbckpconfig.BackupStatusSuccess},
+		{"20230118182654", "20230119150000", gpbckpconfig.BackupStatusSuccess},
+	} {
+		backupData := templateBackupConfig()
+		backupData.Timestamp = value.timestamp
+		backupData.DateDeleted = value.dateDeleted
+		backupData.Status = value.status
+		backups = append(backups, backupData)
+	}
+	historyFile := fakeHistoryFileBackups(t, backups...)
+	defer os.Remove(historyFile)
+	hDB, err := gpbckpconfig.OpenHistoryDB(historyFile)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer hDB.Close()
+	got, err := loadPendingDeletionsDB(context.Background(), hDB)
+	if err != nil {
+		t.Fatalf("\nGet error during load pending deletions:\n%v", err)
+	}
+	want := pendingDeletionMap{
+		"20230118152654": gpbckpconfig.DateDeletedInProgress,
+		"20230118162654": gpbckpconfig.DateDeletedLocalFailed,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestLoadLastBackupsDB(t *testing.T) {
+	backups := make([]gpbckpconfig.BackupConfig, 0, 4)
+	for _, value := range []struct {
+		timestamp, endTime, status string
+		incremental                bool
+	}{
+		{"20230118152654", "20230118152656", gpbckpconfig.BackupStatusSuccess, false},
+		{"20230118162654", "20230118162656", gpbckpconfig.BackupStatusSuccess, false},
+		{"20230118172654", "20230118172656", gpbckpconfig.BackupStatusFailure, false},
+		{"20230118182654", "20230118182656", gpbckpconfig.BackupStatusSuccess, true},
+	} {
+		backupData := templateBackupConfig()
+		backupData.Timestamp = value.timestamp
+		backupData.EndTime = value.endTime
+		backupData.Status = value.status
+		backupData.Incremental = value.incremental
+		backups = append(backups, backupData)
+	}
+	historyFile := fakeHistoryFileBackups(t, backups...)
+	defer os.Remove(historyFile)
+	hDB, err := gpbckpconfig.OpenHistoryDB(historyFile)
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer hDB.Close()
+	// End time is parsed in local timezone, see addLastBackup.
+	localTime := func(sTime string) time.Time {
+		rTime, err := time.ParseInLocation(gpbckpconfig.Layout, sTime, time.Local)
+		if err != nil {
+			t.Fatalf("Failed to parse time: %v", err)
+		}
+		return rTime
+	}
+	tests := []struct {
+		name   string
+		filter backupFilter
+		want   lastBackupMap
+	}{
+		{
+			"AllTypes",
+			backupFilter{collectFailed: true},
+			lastBackupMap{"test": backupMap{
+				"full":        localTime("20230118162656"),
+				"incremental": localTime("20230118182656"),
+			}},
+		},
+		{
+			"DepthIgnored",
+			backupFilter{backupType: "full", timestampAfter: "20230118200000"},
+			lastBackupMap{"test": backupMap{"full": localTime("20230118162656")}},
+		},
+		{
+			"ExcludedDB",
+			backupFilter{dbExclude: []string{"test"}},
+			lastBackupMap{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadLastBackupsDB(context.Background(), hDB, tt.filter)
+			if err != nil {
+				t.Fatalf("\nGet error during load last backups:\n%v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRecoveryPointsDB(t *testing.T) {
+	chain := templateIncrementalChain()
+	metadataOnly := templateBackupConfig()
+	metadataOnly.Timestamp = "20230118180000"
+	metadataOnly.MetadataOnly = true
+	failed := templateBackupConfig()
+	failed.Timestamp = "20230118190000"
+	failed.Status = gpbckpconfig.BackupStatusFailure
+	// The first incremental backup is deleted, so the second one can't be restored.
+	brokenChain := templateIncrementalChain()
+	brokenChain[1].DateDeleted = "20230119150000"
+	// Start time is parsed in local timezone.
+	localTime := func(sTime string) time.Time {
+		rTime, err := time.ParseInLocation(gpbckpconfig.Layout, sTime, time.Local)
+		if err != nil {
+			t.Fatalf("Failed to parse time: %v", err)
+		}
+		return rTime
+	}
+	tests := []struct {
+		name    string
+		backups []gpbckpconfig.BackupConfig
+		filter  backupFilter
+		want    recoveryPointMap
+	}{
+		{
+			"BackupTypeAndDepthIgnored",
+			append(chain, metadataOnly, failed),
+			backupFilter{collectFailed: true, backupType: "full", timestampAfter: "20230118200000"},
+			recoveryPointMap{"test": localTime("20230118170000")},
+		},
+		{
+			"BrokenChain",
+			brokenChain,
+			backupFilter{collectDeleted: true},
+			recoveryPointMap{"test": localTime("20230118150000")},
+		},
+		{
+			"ExcludedDB",
+			chain,
+			backupFilter{dbExclude: []string{"test"}},
+			recoveryPointMap{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			historyFile := fakeHistoryFileBackups(t, tt.backups...)
+			defer os.Remove(historyFile)
+			hDB, err := gpbckpconfig.OpenHistoryDB(historyFile)
+			if err != nil {
+				t.Fatalf("Failed to open test database: %v", err)
+			}
+			defer hDB.Close()
+			got, err := loadRecoveryPointsDB(context.Background(), hDB, tt.filter)
+			if err != nil {
+				t.Fatalf("\nGet error during load recovery points:\n%v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadIncrementalChainsDB(t *testing.T) {
+	chain := templateIncrementalChain()
+	dataOnly := templateBackupConfig()
+	dataOnly.Timestamp = "20230118180000"
+	dataOnly.DataOnly = true
+	newFull := templateBackupConfig()
+	newFull.Timestamp = "20230118190000"
+	failedFull := templateBackupConfig()
+	failedFull.Timestamp = "20230118190000"
+	failedFull.Status = gpbckpconfig.BackupStatusFailure
+	tests := []struct {
+		name    string
+		backups []gpbckpconfig.BackupConfig
+		filter  backupFilter
+		want    map[string]int
+	}{
+		{
+			"BackupTypeAndDepthIgnored",
+			append(chain, dataOnly),
+			backupFilter{backupType: "full", timestampAfter: "20230118200000"},
+			map[string]int{"test": 2},
+		},
+		{
+			"NewFullBackup",
+			append(chain, newFull),
+			backupFilter{},
+			map[string]int{"test": 0},
+		},
+		{
+			"FailedFullBackup",
+			append(chain, failedFull),
+			backupFilter{collectFailed: true},
+			map[string]int{"test": 2},
+		},
+		{
+			"ExcludedDB",
+			chain,
+			backupFilter{dbExclude: []string{"test"}},
+			map[string]int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			historyFile := fakeHistoryFileBackups(t, tt.backups...)
+			defer os.Remove(historyFile)
+			hDB, err := gpbckpconfig.OpenHistoryDB(historyFile)
+			if err != nil {
+				t.Fatalf("Failed to open test database: %v", err)
+			}
+			defer hDB.Close()
+			got, err := loadIncrementalChainsDB(context.Background(), hDB, tt.filter)
+			if err != nil {
+				t.Fatalf("\nGet error during load incremental chains:\n%v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkLoadBackupConfigsDB(b *testing.B) {
+	historyFile := fakeLargeHistoryFile(b, 5000)
+	defer os.Remove(historyFile)
+	hDB, err := gpbckpconfig.OpenHistoryDB(historyFile)
+	if err != nil {
+		b.Fatalf("Failed to open test database: %v", err)
+	}
+	defer hDB.Close()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := loadBackupConfigsDB(context.Background(), hDB, backupFilter{collectDeleted: true, collectFailed: true}); err != nil {
+			b.Fatalf("\nGet error during load backups:\n%v", err)
+		}
+	}
+}
+
+// The previous approach for comparison: one query for backup list
+// and a query set for each backup.
+func BenchmarkGetBackupDataDB(b *testing.B) {
+	historyFile := fakeLargeHistoryFile(b, 5000)
+	defer os.Remove(historyFile)
+	hDB, err := gpbckpconfig.OpenHistoryDB(historyFile)
+	if err != nil {
+		b.Fatalf("Failed to open test database: %v", err)
+	}
+	defer hDB.Close()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		getBackupConfigsPerBackupDB(b, hDB, true, true)
+	}
+}
+
+// Get backup configs via gpbckpconfig functions, one backup at a time.
+func getBackupConfigsPerBackupDB(tb testing.TB, hDB *sql.DB, collectDeleted, collectFailed bool) []gpbckpconfig.BackupConfig {
+	backupList, err := gpbckpconfig.GetBackupNamesDB(collectDeleted, collectFailed, hDB)
+	if err != nil {
+		tb.Fatalf("\nGet error during get backup names:\n%v", err)
+	}
+	backupConfigs := make([]gpbckpconfig.BackupConfig, 0, len(backupList))
+	for _, backupName := range backupList {
+		backupData, err := gpbckpconfig.GetBackupDataDB(backupName, hDB)
+		if err != nil {
+			tb.Fatalf("\nGet error during get backup data:\n%v", err)
+		}
+		backupConfigs = append(backupConfigs, backupData)
+	}
+	return backupConfigs
+}
+
+// Create history database with specified backups.
+func fakeHistoryFileBackups(tb testing.TB, backupConfigs ...gpbckpconfig.BackupConfig) string {
+	tempFile, err := os.CreateTemp("", "gpbackup_history*.db")
+	if err != nil {
+		tb.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer tempFile.Close()
+	hDB, err := history.InitializeHistoryDatabase(tempFile.Name())
+	if err != nil {
+		tb.Fatalf("Failed to initialize history database: %v", err)
+	}
+	defer hDB.Close()
+	for _, backupConfig := range backupConfigs {
+		hBackupConfig := gpbckpconfig.ConvertToHistoryBackupConfig(backupConfig)
+		if err := history.StoreBackupHistory(hDB, &hBackupConfig); err != nil {
+			tb.Fatalf("Failed to store backup history: %v", err)
+		}
+	}
+	return tempFile.Name()
+}
+
+// Create large history database.
+// Every 7th backup is full, others are incremental backups with restore plan.
+// All data is inserted within one transaction to speed up generation.
+func fakeLargeHistoryFile(tb testing.TB, count int) string {
+	tempFile, err := os.CreateTemp("", "gpbackup_history*.db")
+	if err != nil {
+		tb.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer tempFile.Close()
+	hDB, err := history.InitializeHistoryDatabase(tempFile.Name())
+	if err != nil {
+		tb.Fatalf("Failed to initialize history database: %v", err)
+	}
+	defer hDB.Close()
+	tx, err := hDB.Begin()
+	if err != nil {
+		tb.Fatalf("Failed to begin transaction: %v", err)
+	}
+	startTime := returnTimeTime("20200101000000")
+	lastFull := ""
+	for i := 0; i < count; i++ {
+		timestamp := startTime.Add(time.Duration(i) * time.Hour).Format(gpbckpconfig.Layout)
+		endTime := startTime.Add(time.Duration(i)*time.Hour + 10*time.Minute).Format(gpbckpconfig.Layout)
+		incremental := i%7 != 0
+		if !incremental {
+			lastFull = timestamp
+		}
+		_, err = tx.Exec(`INSERT INTO backups (
+			timestamp, backup_dir, backup_version, compressed, compression_type, database_name,
+			database_version, segment_count, data_only, date_deleted, exclude_schema_filtered,
+			exclude_table_filtered, include_schema_filtered, include_table_filtered, incremental,
+			leaf_partition_data, metadata_only, plugin, plugin_version, single_data_file, end_time,
+			without_globals, with_statistics, status)
+			VALUES (?, '/data/backups', '1.30.5', 1, 'gzip', ?, '6.23.0', 2, 0, '', 0, 0, 1, 0, ?,
+			1, 0, '', '', 0, ?, 0, 0, 'Success');`,
+			timestamp, fmt.Sprintf("db%d", i%10), incremental, endTime)
+		if err != nil {
+			tb.Fatalf("Failed to insert backup: %v", err)
+		}
+		_, err = tx.Exec("INSERT INTO include_schemas VALUES (?, 'public');", timestamp)
+		if err != nil {
+			tb.Fatalf("Failed to insert include schema: %v", err)
+		}
+		if incremental {
+			for _, restorePlanTimestamp := range []string{lastFull, timestamp} {
+				_, err = tx.Exec("INSERT INTO restore_plans VALUES (?, ?);", timestamp, restorePlanTimestamp)
+				if err != nil {
+					tb.Fatalf("Failed to insert restore plan: %v", err)
+				}
+				_, err = tx.Exec("INSERT INTO restore_plan_tables VALUES (?, ?, 'public.t1');", timestamp, restorePlanTimestamp)
+				if err != nil {
+					tb.Fatalf("Failed to insert restore plan table: %v", err)
+				}
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		tb.Fatalf("Failed to commit transaction: %v", err)
+	}
+	return tempFile.Name()
+}
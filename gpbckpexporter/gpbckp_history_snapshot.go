@@ -0,0 +1,194 @@
+package gpbckpexporter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Number of attempts to get consistent copy of history file.
+const snapshotCopyAttempts = 3
+
+// Copy of history file, which is read instead of original file.
+// SQLite locking is unreliable on NFS and other shared storages,
+// so in snapshot copy mode history file is copied to local directory each collection.
+type historySnapshot struct {
+	// Path to copy of history file.
+	file string
+	// Time when copy was taken.
+	time time.Time
+	// Size of copy in bytes.
+	size int64
+}
+
+// Take consistent copy of history file into new temporary directory inside dir.
+// Copy is consistent, if checksum of history file is not changed during copying
+// and there are no changes outside history file (see checkPendingChanges).
+// Empty dir means default directory for temporary files.
+func copyHistoryFile(historyFile, dir string) (*historySnapshot, error) {
+	if err := checkPendingChanges(historyFile); err != nil {
+		return nil, err
+	}
+	tempDir, err := os.MkdirTemp(dir, "gpbackup_exporter")
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &historySnapshot{file: filepath.Join(tempDir, filepath.Base(historyFile))}
+	for attempt := 0; attempt < snapshotCopyAttempts; attempt++ {
+		snapshot.time = time.Now()
+		copySum, size, err := copyFileWithChecksum(historyFile, snapshot.file)
+		if err != nil {
+			snapshot.remove()
+			return nil, err
+		}
+		sourceSum, err := fileChecksum(historyFile)
+		if err != nil {
+			snapshot.remove()
+			return nil, err
+		}
+		// Write transaction may be started during copying.
+		if err := checkPendingChanges(historyFile); err != nil {
+			snapshot.remove()
+			return nil, err
+		}
+		if bytes.Equal(copySum, sourceSum) {
+			snapshot.size = size
+			return snapshot, nil
+		}
+	}
+	snapshot.remove()
+	return nil, &historyError{
+		kind: errFileChanged,
+		err:  fmt.Errorf("history file changed during copying %d times", snapshotCopyAttempts),
+	}
+}
+
+// Check that all changes of history database are in history file.
+// Only history file is copied, so copy is stale or inconsistent, if there are:
+//   - not empty -journal file, write transaction is in progress or was interrupted;
+//   - not empty -wal file, history database is in WAL mode and changes aren't checkpointed yet.
+//
+// Such copy is refused with the same error as for changed history file, so reading is retried.
+func checkPendingChanges(historyFile string) error {
+	for _, suffix := range []string{"-journal", "-wal"} {
+		fileInfo, err := os.Stat(historyFile + suffix)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if fileInfo.Size() != 0 {
+			return &historyError{
+				kind: errFileChanged,
+				err:  fmt.Errorf("history file has pending changes in %s", filepath.Base(historyFile+suffix)),
+			}
+		}
+	}
+	return nil
+}
+
+// Remove copy of history file with its temporary directory.
+func (s *historySnapshot) remove() error {
+	return os.RemoveAll(filepath.Dir(s.file))
+}
+
+// Copy file and get checksum of copied data.
+func copyFileWithChecksum(src, dst string) ([]byte, int64, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, 0, err
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(out, hash), in)
+	if errClose := out.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return hash.Sum(nil), size, nil
+}
+
+// Get checksum of file.
+func fileChecksum(file string) ([]byte, error) {
+	in, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, in); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
+
+// snapshotCollector implements prometheus.Collector for history file copies.
+// Age is calculated during scrape, so it grows between collections.
+type snapshotCollector struct {
+	mu        sync.Mutex
+	snapshots map[string]historySnapshot
+	ageDesc   *prometheus.Desc
+	sizeDesc  *prometheus.Desc
+}
+
+var gpbckpSnapshotCollector = &snapshotCollector{
+	snapshots: make(map[string]historySnapshot),
+	ageDesc: prometheus.NewDesc(
+		"gpbackup_exporter_history_snapshot_age_seconds",
+		"Age of the copy of history file used by the last collection in seconds.",
+		[]string{"cluster"},
+		nil,
+	),
+	sizeDesc: prometheus.NewDesc(
+		"gpbackup_exporter_history_snapshot_size_bytes",
+		"Size of the copy of history file used by the last collection in bytes.",
+		[]string{"cluster"},
+		nil,
+	),
+}
+
+// Describe implements prometheus.Collector.
+func (c *snapshotCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.ageDesc
+	ch <- c.sizeDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *snapshotCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for cluster, snapshot := range c.snapshots {
+		ch <- prometheus.MustNewConstMetric(c.ageDesc, prometheus.GaugeValue, time.Since(snapshot.time).Seconds(), cluster)
+		ch <- prometheus.MustNewConstMetric(c.sizeDesc, prometheus.GaugeValue, float64(snapshot.size), cluster)
+	}
+}
+
+// Set copy of history file used by the last collection for cluster.
+func (c *snapshotCollector) set(cluster string, snapshot historySnapshot) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.snapshots[cluster] = snapshot
+}
+
+// Reset removes all snapshots.
+func (c *snapshotCollector) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.snapshots = make(map[string]historySnapshot)
+}
@@ -0,0 +1,149 @@
+package gpbckpexporter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+func TestCopyHistoryFile(t *testing.T) {
+	dir := t.TempDir()
+	historyFile := fakeHistoryFileBackups(t, templateBackupConfig())
+	defer os.Remove(historyFile)
+	snapshot, err := copyHistoryFile(historyFile, dir)
+	if err != nil {
+		t.Fatalf("\nGet error during copy history file:\n%v", err)
+	}
+	want, err := os.ReadFile(historyFile)
+	if err != nil {
+		t.Fatalf("Failed to read test file: %v", err)
+	}
+	got, err := os.ReadFile(snapshot.file)
+	if err != nil {
+		t.Fatalf("\nGet error during read copy:\n%v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("\nVariables do not match:\ncopy differs from history file")
+	}
+	if snapshot.size != int64(len(want)) {
+		t.Errorf("\nVariables do not match:\n%d\nwant:\n%d", snapshot.size, len(want))
+	}
+	if err := snapshot.remove(); err != nil {
+		t.Errorf("\nGet error during remove copy:\n%v", err)
+	}
+	assertDirEmpty(t, dir)
+}
+
+func TestCopyHistoryFileNotFound(t *testing.T) {
+	dir := t.TempDir()
+	_, err := copyHistoryFile(filepath.Join(dir, "missing.db"), dir)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", err, os.ErrNotExist)
+	}
+	assertDirEmpty(t, dir)
+}
+
+func TestCopyHistoryFilePendingChanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		suffix  string
+		data    []byte
+		wantErr error
+	}{
+		{"Journal", "-journal", []byte("data"), errFileChanged},
+		{"WAL", "-wal", []byte("data"), errFileChanged},
+		{"EmptyWAL", "-wal", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			historyFile := fakeHistoryFileBackups(t, templateBackupConfig())
+			defer os.Remove(historyFile)
+			if err := os.WriteFile(historyFile+tt.suffix, tt.data, 0600); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+			defer os.Remove(historyFile + tt.suffix)
+			snapshot, err := copyHistoryFile(historyFile, dir)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", err, tt.wantErr)
+			}
+			if snapshot != nil {
+				snapshot.remove()
+			}
+			assertDirEmpty(t, dir)
+		})
+	}
+}
+
+func TestParseBackupDataSnapshotCopy(t *testing.T) {
+	defer SetHistoryDBOptions(historyDBOptions)
+	dir := t.TempDir()
+	SetHistoryDBOptions(HistoryDBOptions{BusyTimeout: time.Second, SnapshotCopy: true, SnapshotDir: dir})
+	historyFile := fakeHistoryFileBackups(t, templateBackupConfig())
+	defer os.Remove(historyFile)
+	defer delete(historyCaches, historyFile)
+	hData, _, stats, err := parseBackupData(context.Background(), historyFile, backupFilter{}, getLogger())
+	if err != nil {
+		t.Fatalf("\nGet error during parse backup data:\n%v", err)
+	}
+	if len(hData.BackupConfigs) != 1 {
+		t.Errorf("\nVariables do not match:\n%d\nwant:\n%d", len(hData.BackupConfigs), 1)
+	}
+	if stats.snapshot == nil || stats.snapshot.size == 0 {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\nsnapshot with non-zero size", stats.snapshot)
+	}
+	// Cache is bound to original history file.
+	if _, ok := historyCaches[historyFile]; !ok {
+		t.Errorf("\nNo cache for history file:\n%s", historyFile)
+	}
+	// Copy is removed after reading.
+	assertDirEmpty(t, dir)
+}
+
+func TestSnapshotCollector(t *testing.T) {
+	collector := &snapshotCollector{
+		snapshots: make(map[string]historySnapshot),
+		ageDesc:   gpbckpSnapshotCollector.ageDesc,
+		sizeDesc:  gpbckpSnapshotCollector.sizeDesc,
+	}
+	collector.set("test", historySnapshot{time: time.Now().Add(-time.Minute), size: 4096})
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collector)
+	metricFamily, err := reg.Gather()
+	if err != nil {
+		t.Errorf("\nGet error during gather:\n%v", err)
+	}
+	out := &bytes.Buffer{}
+	for _, mf := range metricFamily {
+		if _, err := expfmt.MetricFamilyToText(out, mf); err != nil {
+			panic(err)
+		}
+	}
+	for _, text := range []string{
+		`gpbackup_exporter_history_snapshot_age_seconds{cluster="test"} 60`,
+		`gpbackup_exporter_history_snapshot_size_bytes{cluster="test"} 4096`,
+	} {
+		if !strings.Contains(out.String(), text) {
+			t.Errorf("\nMetrics don't contain:\n%s\nmetrics:\n%s", text, out.String())
+		}
+	}
+}
+
+// Check that directory is empty.
+func assertDirEmpty(tb testing.TB, dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		tb.Fatalf("Failed to read directory: %v", err)
+	}
+	if len(entries) != 0 {
+		tb.Errorf("\nVariables do not match:\n%d\nwant:\n%d", len(entries), 0)
+	}
+}
@@ -0,0 +1,162 @@
+package gpbckpexporter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/greenplum-db/gpbackup/history"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+// Legacy gpbackup versions store history in YAML file gpbackup_history.yaml.
+
+// Format of history file.
+type historyFormat int
+
+const (
+	historyFormatUnknown historyFormat = iota
+	historyFormatSQLite
+	historyFormatYAML
+)
+
+// Header of SQLite database file.
+const sqliteHeader = "SQLite format 3\x00"
+
+// Maximum size of history file beginning, which is read to detect format.
+const formatDetectSize = 4096
+
+// Detect format of history file by its content.
+// If file can't be read or its content doesn't match any format,
+// e.g. file is empty, format is detected by file extension.
+func detectHistoryFormat(historyFile string) historyFormat {
+	if header, err := readFileHeader(historyFile, formatDetectSize); err == nil {
+		switch {
+		case strings.HasPrefix(header, sqliteHeader):
+			return historyFormatSQLite
+		case isYAMLHistory(header):
+			return historyFormatYAML
+		}
+	}
+	switch filepath.Ext(historyFile) {
+	case ".db":
+		return historyFormatSQLite
+	case ".yaml", ".yml":
+		return historyFormatYAML
+	}
+	return historyFormatUnknown
+}
+
+// Read up to size bytes from the beginning of file.
+func readFileHeader(file string, size int) (string, error) {
+	in, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	header := make([]byte, size)
+	n, err := io.ReadFull(in, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	return string(header[:n]), nil
+}
+
+// Check that the first meaningful line of YAML document is backupconfigs key.
+// Empty lines, comments and document start marker are skipped.
+func isYAMLHistory(header string) bool {
+	scanner := bufio.NewScanner(strings.NewReader(header))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line == "---" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return strings.HasPrefix(line, "backupconfigs:")
+	}
+	return false
+}
+
+// Get data from YAML history file.
+// Backups from YAML file are stored into temporary history database
+// and read from it in the same way as from history database.
+// Snapshot copy mode and cache aren't used, because the whole file is read at once.
+func getDataFromHistoryYAML(ctx context.Context, historyFile string, filter backupFilter, logger *slog.Logger) (gpbckpconfig.History, []string, historyStats, error) {
+	var hData gpbckpconfig.History
+	data, err := gpbckpconfig.ReadHistoryFile(historyFile)
+	if err != nil {
+		logger.Error("Read gpbackup history file failed", "err", err)
+		return hData, nil, historyStats{}, newHistoryError(err)
+	}
+	if err := ctx.Err(); err != nil {
+		return hData, nil, historyStats{}, err
+	}
+	parseHData, err := gpbckpconfig.ParseResult(data)
+	if err != nil {
+		logger.Error("Parse gpbackup history file failed", "err", err)
+		return hData, nil, historyStats{}, &historyError{errCorruptFile, err}
+	}
+	dbFile, err := storeHistoryDB(ctx, parseHData.BackupConfigs, historyDBOptions.SnapshotDir)
+	if err != nil {
+		logger.Error("Convert gpbackup history file failed", "err", err)
+		return hData, nil, historyStats{}, err
+	}
+	defer func() {
+		if errRemove := os.RemoveAll(filepath.Dir(dbFile)); errRemove != nil {
+			logger.Error("Remove converted gpbackup history file failed", "err", errRemove)
+		}
+	}()
+	hData, dbNames, stats, err := readHistoryDB(ctx, dbFile, "", filter, historyStats{}, logger)
+	// The whole YAML file is read.
+	stats.rowsRead = len(parseHData.BackupConfigs)
+	return hData, dbNames, stats, err
+}
+
+// Store backups into new history database inside new temporary directory inside dir.
+// Empty dir means default directory for temporary files.
+// Returns path to history database file.
+func storeHistoryDB(ctx context.Context, backupConfigs []gpbckpconfig.BackupConfig, dir string) (string, error) {
+	tempDir, err := os.MkdirTemp(dir, "gpbackup_exporter")
+	if err != nil {
+		return "", err
+	}
+	dbFile := filepath.Join(tempDir, "gpbackup_history.db")
+	if err := storeBackupConfigs(ctx, dbFile, backupConfigs); err != nil {
+		os.RemoveAll(tempDir)
+		return "", err
+	}
+	return dbFile, nil
+}
+
+func storeBackupConfigs(ctx context.Context, dbFile string, backupConfigs []gpbckpconfig.BackupConfig) error {
+	hDB, err := history.InitializeHistoryDatabase(dbFile)
+	if err != nil {
+		return err
+	}
+	defer hDB.Close()
+	// Temporary database doesn't need durability.
+	hDB.SetMaxOpenConns(1)
+	if _, err := hDB.ExecContext(ctx, "PRAGMA synchronous = OFF; PRAGMA journal_mode = MEMORY;"); err != nil {
+		return err
+	}
+	for _, backupConfig := range backupConfigs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hBackupConfig := gpbckpconfig.ConvertToHistoryBackupConfig(backupConfig)
+		if err := history.StoreBackupHistory(hDB, &hBackupConfig); err != nil {
+			return &historyError{errCorruptFile, fmt.Errorf("store backup %s: %w", backupConfig.Timestamp, err)}
+		}
+		// End time is set to current time for backups without end time, see history.StoreBackupHistory.
+		if backupConfig.EndTime == "" {
+			if _, err := hDB.ExecContext(ctx, "UPDATE backups SET end_time = '' WHERE timestamp = ?;", backupConfig.Timestamp); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package gpbckpexporter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+	"go.yaml.in/yaml/v2"
+)
+
+// Create YAML history file with backups.
+func fakeHistoryFileYAML(tb testing.TB, backupConfigs ...gpbckpconfig.BackupConfig) string {
+	data, err := yaml.Marshal(gpbckpconfig.History{BackupConfigs: backupConfigs})
+	if err != nil {
+		tb.Fatalf("Failed to marshal history: %v", err)
+	}
+	file := filepath.Join(tb.TempDir(), "gpbackup_history.yaml")
+	if err := os.WriteFile(file, data, 0600); err != nil {
+		tb.Fatalf("Failed to create test file: %v", err)
+	}
+	return file
+}
+
+func TestDetectHistoryFormat(t *testing.T) {
+	dir := t.TempDir()
+	dbFile := fakeHistoryFileBackups(t, templateBackupConfig())
+	defer os.Remove(dbFile)
+	yamlFile := fakeHistoryFileYAML(t, templateBackupConfig())
+	files := map[string]string{
+		"yaml_content.db": "# gpbackup history\n---\nbackupconfigs:\n- backupdir: /data/backups\n",
+		"empty.yaml":      "",
+		"empty.db":        "",
+		"text.txt":        "this is plain text file",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	sqliteContentFile := filepath.Join(dir, "sqlite_content.yaml")
+	data, err := os.ReadFile(dbFile)
+	if err != nil {
+		t.Fatalf("Failed to read test file: %v", err)
+	}
+	if err := os.WriteFile(sqliteContentFile, data, 0600); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	tests := []struct {
+		name        string
+		historyFile string
+		want        historyFormat
+	}{
+		{"SQLite", dbFile, historyFormatSQLite},
+		{"YAML", yamlFile, historyFormatYAML},
+		{"SQLiteContentWithYAMLExtension", sqliteContentFile, historyFormatSQLite},
+		{"YAMLContentWithDBExtension", filepath.Join(dir, "yaml_content.db"), historyFormatYAML},
+		{"EmptyYAML", filepath.Join(dir, "empty.yaml"), historyFormatYAML},
+		{"EmptyDB", filepath.Join(dir, "empty.db"), historyFormatSQLite},
+		{"MissingYAML", filepath.Join(dir, "missing.yml"), historyFormatYAML},
+		{"MissingDB", filepath.Join(dir, "missing.db"), historyFormatSQLite},
+		{"UnknownFormat", filepath.Join(dir, "text.txt"), historyFormatUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectHistoryFormat(tt.historyFile); got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+// YAML and SQLite history files with the same backups must give the same result.
+func TestGetDataFromHistoryYAMLSameAsDB(t *testing.T) {
+	full := templateBackupConfig()
+	full.Timestamp = "20230118152654"
+	incremental := templateBackupConfig()
+	incremental.Timestamp = "20230119152654"
+	incremental.Incremental = true
+	incremental.IncludeSchemaFiltered = true
+	incremental.IncludeSchemas = []string{"public"}
+	incremental.RestorePlan = []gpbckpconfig.RestorePlanEntry{
+		{Timestamp: "20230118152654", TableFQNs: []string{"public.t1", "public.t2"}},
+		{Timestamp: "20230119152654", TableFQNs: []string{"public.t1"}},
+	}
+	failed := templateBackupConfig()
+	failed.Timestamp = "20230120152654"
+	failed.DatabaseName = "test2"
+	failed.Status = gpbckpconfig.BackupStatusFailure
+	deleted := templateBackupConfig()
+	deleted.Timestamp = "20230121152654"
+	deleted.DatabaseName = "test2"
+	deleted.DateDeleted = "20230125152654"
+	deletionInProgress := templateBackupConfig()
+	deletionInProgress.Timestamp = "20230122152654"
+	deletionInProgress.DateDeleted = gpbckpconfig.DateDeletedInProgress
+	metadataOnly := templateBackupConfig()
+	metadataOnly.Timestamp = "20230123152654"
+	metadataOnly.DatabaseName = "test3"
+	metadataOnly.MetadataOnly = true
+	metadataOnly.ExcludeTableFiltered = true
+	metadataOnly.ExcludeRelations = []string{"public.t3"}
+	inProgress := templateBackupConfig()
+	inProgress.Timestamp = "20230124152654"
+	inProgress.Status = gpbckpconfig.BackupStatusInProgress
+	inProgress.EndTime = ""
+	backupConfigs := []gpbckpconfig.BackupConfig{full, incremental, failed, deleted, deletionInProgress, metadataOnly, inProgress}
+	dbFile := fakeHistoryFileBackups(t, backupConfigs...)
+	defer os.Remove(dbFile)
+	defer delete(historyCaches, dbFile)
+	// End time is set for backups without it during storing.
+	fakeHistoryDB(t, dbFile, fmt.Sprintf("UPDATE backups SET end_time = '' WHERE timestamp = '%s';", inProgress.Timestamp))
+	yamlFile := fakeHistoryFileYAML(t, backupConfigs...)
+	tests := []struct {
+		name   string
+		filter backupFilter
+	}{
+		{"WithoutFilters", backupFilter{}},
+		{"CollectDeleted", backupFilter{collectDeleted: true}},
+		{"CollectFailed", backupFilter{collectFailed: true}},
+		{"CollectDeletedAndFailed", backupFilter{collectDeleted: true, collectFailed: true}},
+		{"BackupTypeIncremental", backupFilter{backupType: gpbckpconfig.BackupTypeIncremental}},
+		{"BackupTypeMetadataOnly", backupFilter{collectFailed: true, backupType: gpbckpconfig.BackupTypeMetadataOnly}},
+		{"DBInclude", backupFilter{collectDeleted: true, dbInclude: []string{"test2"}}},
+		{"DBExclude", backupFilter{dbExclude: []string{"test"}}},
+		{"DBIncludeAndExclude", backupFilter{dbInclude: []string{"test", "test3"}, dbExclude: []string{"test", "test2"}, backupType: gpbckpconfig.BackupTypeFull}},
+		{"Depth", backupFilter{collectFailed: true, timestampAfter: "20230119152654"}},
+		{"Timestamps", backupFilter{timestamps: []string{"20230119152654", "20230123152654"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wantHData, wantDBNames, wantStats, err := parseBackupData(context.Background(), dbFile, tt.filter, getLogger())
+			if err != nil {
+				t.Fatalf("\nUnexpected error for history db:\n%v", err)
+			}
+			gotHData, gotDBNames, gotStats, err := parseBackupData(context.Background(), yamlFile, tt.filter, getLogger())
+			if err != nil {
+				t.Fatalf("\nUnexpected error for yaml file:\n%v", err)
+			}
+			if !reflect.DeepEqual(gotHData, wantHData) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", gotHData, wantHData)
+			}
+			if !reflect.DeepEqual(gotDBNames, wantDBNames) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", gotDBNames, wantDBNames)
+			}
+			// The whole yaml file is read, but only selected rows are read from history database.
+			if gotStats.rowsRead != len(backupConfigs) {
+				t.Errorf("\nVariables do not match:\n%d\nwant:\n%d", gotStats.rowsRead, len(backupConfigs))
+			}
+			gotStats.rowsRead = wantStats.rowsRead
+			if !reflect.DeepEqual(gotStats, wantStats) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", gotStats, wantStats)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package gpbckpexporter
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+var (
+	gpbckpBackupInProgressMetric               = newBackupInProgressMetric()
+	gpbckpBackupInProgressElapsedSecondsMetric = newBackupInProgressElapsedSecondsMetric()
+	gpbckpBackupInProgressStuckMetric          = newBackupInProgressStuckMetric()
+)
+
+// Set in progress backup metrics:
+//   - gpbackup_backup_in_progress
+//   - gpbackup_backup_in_progress_elapsed_seconds
+//   - gpbackup_backup_in_progress_stuck
+//
+// Metrics are set only for backups with status "In Progress".
+// Backup is stuck, when it's in progress longer than threshold in seconds.
+// It usually means that gpbackup crashed without updating history file.
+// Threshold 0 - stuck backups aren't detected.
+func getBackupInProgressMetrics(cluster, bckpType string, backupData gpbckpconfig.BackupConfig, threshold int, currentUnixTime int64, setUpMetricValueFun setUpMetricValueFunType, logger *slog.Logger) {
+	if !backupData.IsInProgress() {
+		return
+	}
+	setUpMetric(
+		gpbckpBackupInProgressMetric,
+		"gpbackup_backup_in_progress",
+		1,
+		setUpMetricValueFun,
+		logger,
+		bckpType,
+		cluster,
+		backupData.DatabaseName,
+		backupData.Timestamp,
+	)
+	// The same as for backup timestamp, timezone of Greenplum cluster is taken into account.
+	startTime, err := time.ParseInLocation(gpbckpconfig.Layout, backupData.Timestamp, time.Local)
+	if err != nil {
+		logger.Error("Parse backup timestamp value failed", "err", err)
+		return
+	}
+	elapsed := time.Unix(currentUnixTime, 0).Sub(startTime)
+	// Seconds since backup start.
+	setUpMetric(
+		gpbckpBackupInProgressElapsedSecondsMetric,
+		"gpbackup_backup_in_progress_elapsed_seconds",
+		elapsed.Seconds(),
+		setUpMetricValueFun,
+		logger,
+		bckpType,
+		cluster,
+		backupData.DatabaseName,
+		backupData.Timestamp,
+	)
+	if threshold <= 0 {
+		return
+	}
+	setUpMetric(
+		gpbckpBackupInProgressStuckMetric,
+		"gpbackup_backup_in_progress_stuck",
+		convertBoolToFloat64(elapsed > time.Duration(threshold)*time.Second),
+		setUpMetricValueFun,
+		logger,
+		bckpType,
+		cluster,
+		backupData.DatabaseName,
+		backupData.Timestamp,
+	)
+}
+
+func resetInProgressMetrics() {
+	gpbckpBackupInProgressMetric.Reset()
+	gpbckpBackupInProgressElapsedSecondsMetric.Reset()
+	gpbckpBackupInProgressStuckMetric.Reset()
+}
+
+func newBackupInProgressMetric() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_backup_in_progress",
+		Help: "Backup with status In Progress.",
+	},
+		[]string{
+			"backup_type",
+			"cluster",
+			"database_name",
+			"timestamp"})
+}
+
+func newBackupInProgressElapsedSecondsMetric() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_backup_in_progress_elapsed_seconds",
+		Help: "Seconds since the start of backup with status In Progress.",
+	},
+		[]string{
+			"backup_type",
+			"cluster",
+			"database_name",
+			"timestamp"})
+}
+
+func newBackupInProgressStuckMetric() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_backup_in_progress_stuck",
+		Help: "Whether backup has status In Progress longer than expected.",
+	},
+		[]string{
+			"backup_type",
+			"cluster",
+			"database_name",
+			"timestamp"})
+}
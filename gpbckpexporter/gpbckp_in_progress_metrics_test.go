@@ -0,0 +1,78 @@
+package gpbckpexporter
+
+import (
+	"testing"
+
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+func TestGetBackupInProgressMetrics(t *testing.T) {
+	inProgress := templateBackupConfig()
+	inProgress.Status = gpbckpconfig.BackupStatusInProgress
+	inProgress.EndTime = ""
+	tests := []struct {
+		name       string
+		backupData gpbckpconfig.BackupConfig
+		threshold  int
+		testText   string
+	}{
+		{
+			"InProgressStuck",
+			inProgress,
+			3600,
+			`# HELP gpbackup_backup_in_progress Backup with status In Progress.
+# TYPE gpbackup_backup_in_progress gauge
+gpbackup_backup_in_progress{backup_type="full",cluster="",database_name="test",timestamp="20230118152654"} 1
+# HELP gpbackup_backup_in_progress_elapsed_seconds Seconds since the start of backup with status In Progress.
+# TYPE gpbackup_backup_in_progress_elapsed_seconds gauge
+gpbackup_backup_in_progress_elapsed_seconds{backup_type="full",cluster="",database_name="test",timestamp="20230118152654"} 16386
+# HELP gpbackup_backup_in_progress_stuck Whether backup has status In Progress longer than expected.
+# TYPE gpbackup_backup_in_progress_stuck gauge
+gpbackup_backup_in_progress_stuck{backup_type="full",cluster="",database_name="test",timestamp="20230118152654"} 1
+`,
+		},
+		{
+			"InProgressNotStuck",
+			inProgress,
+			86400,
+			`# HELP gpbackup_backup_in_progress Backup with status In Progress.
+# TYPE gpbackup_backup_in_progress gauge
+gpbackup_backup_in_progress{backup_type="full",cluster="",database_name="test",timestamp="20230118152654"} 1
+# HELP gpbackup_backup_in_progress_elapsed_seconds Seconds since the start of backup with status In Progress.
+# TYPE gpbackup_backup_in_progress_elapsed_seconds gauge
+gpbackup_backup_in_progress_elapsed_seconds{backup_type="full",cluster="",database_name="test",timestamp="20230118152654"} 16386
+# HELP gpbackup_backup_in_progress_stuck Whether backup has status In Progress longer than expected.
+# TYPE gpbackup_backup_in_progress_stuck gauge
+gpbackup_backup_in_progress_stuck{backup_type="full",cluster="",database_name="test",timestamp="20230118152654"} 0
+`,
+		},
+		{
+			"InProgressWithoutThreshold",
+			inProgress,
+			0,
+			`# HELP gpbackup_backup_in_progress Backup with status In Progress.
+# TYPE gpbackup_backup_in_progress gauge
+gpbackup_backup_in_progress{backup_type="full",cluster="",database_name="test",timestamp="20230118152654"} 1
+# HELP gpbackup_backup_in_progress_elapsed_seconds Seconds since the start of backup with status In Progress.
+# TYPE gpbackup_backup_in_progress_elapsed_seconds gauge
+gpbackup_backup_in_progress_elapsed_seconds{backup_type="full",cluster="",database_name="test",timestamp="20230118152654"} 16386
+`,
+		},
+		{
+			"FinishedBackup",
+			templateBackupConfig(),
+			3600,
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetInProgressMetrics()
+			getBackupInProgressMetrics("", "full", tt.backupData, tt.threshold, templateUnixTime(), setUpMetricValue, getLogger())
+			got := gatherMetricsText(t, gpbckpBackupInProgressMetric, gpbckpBackupInProgressElapsedSecondsMetric, gpbckpBackupInProgressStuckMetric)
+			if got != tt.testText {
+				t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", got, tt.testText)
+			}
+		})
+	}
+}
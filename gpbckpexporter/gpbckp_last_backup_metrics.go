@@ -2,23 +2,46 @@ package gpbckpexporter
 
 import (
 	"log/slog"
+	"math"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
 )
 
-var gpbckpBackupSinceLastCompletionSecondsMetric = promauto.NewGaugeVec(prometheus.GaugeOpts{
-	Name: "gpbackup_backup_since_last_completion_seconds",
-	Help: "Seconds since the last completed backup.",
-},
-	[]string{
-		"backup_type",
-		"database_name"})
+var (
+	gpbckpBackupSinceLastCompletionSecondsMetric = newBackupSinceLastCompletionSecondsMetric()
+	gpbckpDatabaseRPOSecondsMetric               = newDatabaseRPOSecondsMetric()
+	gpbckpDatabaseBackupMissingMetric            = newDatabaseBackupMissingMetric()
+)
+
+// Start time of the newest recovery point by database.
+type recoveryPointMap map[string]time.Time
+
+// Add end time of successful backup to the last backups by database and backup type,
+// if it's later than the known one.
+func addLastBackup(lastBackups lastBackupMap, backupData gpbckpconfig.BackupConfig) error {
+	bckpType, err := backupData.GetBackupType()
+	if err != nil {
+		return err
+	}
+	// See the note about timezone in collectBackupInfo.
+	endTime, err := time.ParseInLocation(gpbckpconfig.Layout, backupData.EndTime, time.Local)
+	if err != nil {
+		return err
+	}
+	if _, ok := lastBackups[backupData.DatabaseName]; !ok {
+		lastBackups[backupData.DatabaseName] = make(backupMap)
+	}
+	if lastTime, ok := lastBackups[backupData.DatabaseName][bckpType]; !ok || lastTime.Before(endTime) {
+		lastBackups[backupData.DatabaseName][bckpType] = endTime
+	}
+	return nil
+}
 
 // Set backup metrics:
 //   - gpbackup_backup_since_last_completion_seconds
-func getBackupLastMetrics(lastBackups lastBackupMap, currentUnixTime int64, setUpMetricValueFun setUpMetricValueFunType, logger *slog.Logger) {
+func getBackupLastMetrics(cluster string, lastBackups lastBackupMap, currentUnixTime int64, setUpMetricValueFun setUpMetricValueFunType, logger *slog.Logger) {
 	for db, bckps := range lastBackups {
 		for bckpType, endTime := range bckps {
 			// Seconds since the last completed backups.
@@ -29,12 +52,116 @@ func getBackupLastMetrics(lastBackups lastBackupMap, currentUnixTime int64, setU
 				setUpMetricValueFun,
 				logger,
 				bckpType,
+				cluster,
 				db,
 			)
 		}
 	}
 }
 
+// Set database metrics:
+//   - gpbackup_database_rpo_seconds
+//
+// Data is saved to backup as of backup start, so RPO is calculated from backup timestamp.
+func getDatabaseRPOMetrics(cluster string, recoveryPoints recoveryPointMap, currentUnixTime int64, setUpMetricValueFun setUpMetricValueFunType, logger *slog.Logger) {
+	for db, startTime := range recoveryPoints {
+		setUpMetric(
+			gpbckpDatabaseRPOSecondsMetric,
+			"gpbackup_database_rpo_seconds",
+			time.Unix(currentUnixTime, 0).Sub(startTime).Seconds(),
+			setUpMetricValueFun,
+			logger,
+			cluster,
+			db,
+		)
+	}
+}
+
+// Get backup type, which is required for expected databases.
+// Without backup type filter, full backup is required.
+func requiredBackupType(backupType string) string {
+	if backupType == "" {
+		return gpbckpconfig.BackupTypeFull
+	}
+	return backupType
+}
+
+// Set expected databases metrics:
+//   - gpbackup_database_backup_missing
+//   - gpbackup_backup_since_last_completion_seconds
+//
+// Last backups are taken regardless of collection depth, so for expected databases
+// gpbackup_backup_since_last_completion_seconds is set even if the last successful backup
+// is older than collection depth. For expected databases without successful backups
+// of required type, it's set to +Inf, so series exists even if database has never been backed up.
+// Only databases selected by databases filters are processed.
+func getDatabaseMissingMetrics(cluster, bckpType string, dbExpected, dbInclude, dbExclude []string, lastBackups lastBackupMap, currentUnixTime int64, setUpMetricValueFun setUpMetricValueFunType, logger *slog.Logger) {
+	for _, db := range dbExpected {
+		if db == "" || !dbSelected(db, dbInclude, dbExclude) {
+			continue
+		}
+		endTime, ok := lastBackups[db][bckpType]
+		setUpMetric(
+			gpbckpDatabaseBackupMissingMetric,
+			"gpbackup_database_backup_missing",
+			convertBoolToFloat64(!ok),
+			setUpMetricValueFun,
+			logger,
+			bckpType,
+			cluster,
+			db,
+		)
+		sinceLastCompletion := math.Inf(1)
+		if ok {
+			sinceLastCompletion = time.Unix(currentUnixTime, 0).Sub(endTime).Seconds()
+		}
+		setUpMetric(
+			gpbckpBackupSinceLastCompletionSecondsMetric,
+			"gpbackup_backup_since_last_completion_seconds",
+			sinceLastCompletion,
+			setUpMetricValueFun,
+			logger,
+			bckpType,
+			cluster,
+			db,
+		)
+	}
+}
+
 func resetLastBackupMetrics() {
 	gpbckpBackupSinceLastCompletionSecondsMetric.Reset()
+	gpbckpDatabaseRPOSecondsMetric.Reset()
+	gpbckpDatabaseBackupMissingMetric.Reset()
+}
+
+func newBackupSinceLastCompletionSecondsMetric() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_backup_since_last_completion_seconds",
+		Help: "Seconds since the last completed backup.",
+	},
+		[]string{
+			"backup_type",
+			"cluster",
+			"database_name"})
+}
+
+func newDatabaseRPOSecondsMetric() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_database_rpo_seconds",
+		Help: "Seconds since the start of the newest restorable backup with user data.",
+	},
+		[]string{
+			"cluster",
+			"database_name"})
+}
+
+func newDatabaseBackupMissingMetric() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_database_backup_missing",
+		Help: "Whether expected database has no successful backups of required type.",
+	},
+		[]string{
+			"backup_type",
+			"cluster",
+			"database_name"})
 }
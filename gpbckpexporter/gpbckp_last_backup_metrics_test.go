@@ -2,13 +2,18 @@ package gpbckpexporter
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"log/slog"
+	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
 )
 
 func TestGetBackupLastMetrics(t *testing.T) {
@@ -19,10 +24,10 @@ func TestGetBackupLastMetrics(t *testing.T) {
 	}
 	templateMetrics := `# HELP gpbackup_backup_since_last_completion_seconds Seconds since the last completed backup.
 # TYPE gpbackup_backup_since_last_completion_seconds gauge
-gpbackup_backup_since_last_completion_seconds{backup_type="data-only",database_name="test"} 7200
-gpbackup_backup_since_last_completion_seconds{backup_type="full",database_name="test"} 18000
-gpbackup_backup_since_last_completion_seconds{backup_type="incremental",database_name="test"} 14400
-gpbackup_backup_since_last_completion_seconds{backup_type="metadata-only",database_name="test"} 10800
+gpbackup_backup_since_last_completion_seconds{backup_type="data-only",cluster="",database_name="test"} 7200
+gpbackup_backup_since_last_completion_seconds{backup_type="full",cluster="",database_name="test"} 18000
+gpbackup_backup_since_last_completion_seconds{backup_type="incremental",cluster="",database_name="test"} 14400
+gpbackup_backup_since_last_completion_seconds{backup_type="metadata-only",cluster="",database_name="test"} 10800
 `
 	tests := []struct {
 		name string
@@ -44,7 +49,7 @@ gpbackup_backup_since_last_completion_seconds{backup_type="metadata-only",databa
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			resetLastBackupMetrics()
-			getBackupLastMetrics(tt.args.lastBackups, templateUnixTime(), tt.args.setUpMetricValueFun, getLogger())
+			getBackupLastMetrics("", tt.args.lastBackups, templateUnixTime(), tt.args.setUpMetricValueFun, getLogger())
 			reg := prometheus.NewRegistry()
 			reg.MustRegister(
 				gpbckpBackupSinceLastCompletionSecondsMetric,
@@ -93,7 +98,7 @@ func TestGetBackupLastMetricsErrorsAndDebugs(t *testing.T) {
 			resetLastBackupMetrics()
 			out := &bytes.Buffer{}
 			lc := slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug}))
-			getBackupLastMetrics(tt.args.lastBackups, templateUnixTime(), tt.args.setUpMetricValueFun, lc)
+			getBackupLastMetrics("", tt.args.lastBackups, templateUnixTime(), tt.args.setUpMetricValueFun, lc)
 			errorsOutputCount := strings.Count(out.String(), "level=ERROR")
 			debugsOutputCount := strings.Count(out.String(), "level=DEBUG")
 			if tt.args.errorsCount != errorsOutputCount || tt.args.debugsCount != debugsOutputCount {
@@ -104,3 +109,120 @@ func TestGetBackupLastMetricsErrorsAndDebugs(t *testing.T) {
 		})
 	}
 }
+
+func TestGetDatabaseRPOMetrics(t *testing.T) {
+	templateMetrics := `# HELP gpbackup_database_rpo_seconds Seconds since the start of the newest restorable backup with user data.
+# TYPE gpbackup_database_rpo_seconds gauge
+gpbackup_database_rpo_seconds{cluster="",database_name="test"} 7200
+`
+	resetLastBackupMetrics()
+	getDatabaseRPOMetrics("", recoveryPointMap{"test": returnTimeTime("20230118180000")}, templateUnixTime(), setUpMetricValue, getLogger())
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		gpbckpDatabaseRPOSecondsMetric,
+	)
+	metricFamily, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("\nGet error during gather metrics:\n%v", err)
+	}
+	out := &bytes.Buffer{}
+	for _, mf := range metricFamily {
+		if _, err := expfmt.MetricFamilyToText(out, mf); err != nil {
+			t.Fatalf("\nGet error during convert metrics:\n%v", err)
+		}
+	}
+	if templateMetrics != out.String() {
+		t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", out.String(), templateMetrics)
+	}
+}
+
+func TestGetDatabaseMissingMetrics(t *testing.T) {
+	templateMetrics := `# HELP gpbackup_backup_since_last_completion_seconds Seconds since the last completed backup.
+# TYPE gpbackup_backup_since_last_completion_seconds gauge
+gpbackup_backup_since_last_completion_seconds{backup_type="full",cluster="",database_name="test"} 18000
+gpbackup_backup_since_last_completion_seconds{backup_type="full",cluster="",database_name="test2"} +Inf
+# HELP gpbackup_database_backup_missing Whether expected database has no successful backups of required type.
+# TYPE gpbackup_database_backup_missing gauge
+gpbackup_database_backup_missing{backup_type="full",cluster="",database_name="test"} 0
+gpbackup_database_backup_missing{backup_type="full",cluster="",database_name="test2"} 1
+`
+	lastBackups := lastBackupMap{
+		"test":  backupMap{"full": returnTimeTime("20230118150000")},
+		"test3": backupMap{"incremental": returnTimeTime("20230118150000")},
+	}
+	resetLastBackupMetrics()
+	getDatabaseMissingMetrics("", requiredBackupType(""), []string{"test", "test2", "test3"}, []string{""}, []string{"test3"}, lastBackups, templateUnixTime(), setUpMetricValue, getLogger())
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		gpbckpBackupSinceLastCompletionSecondsMetric,
+		gpbckpDatabaseBackupMissingMetric,
+	)
+	metricFamily, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("\nGet error during gather metrics:\n%v", err)
+	}
+	out := &bytes.Buffer{}
+	for _, mf := range metricFamily {
+		if _, err := expfmt.MetricFamilyToText(out, mf); err != nil {
+			t.Fatalf("\nGet error during convert metrics:\n%v", err)
+		}
+	}
+	if templateMetrics != out.String() {
+		t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", out.String(), templateMetrics)
+	}
+}
+
+func TestCollectBackupInfoMissingWithDepth(t *testing.T) {
+	templateMetrics := `# HELP gpbackup_backup_sla_violated Whether the last successful backup is older than SLA allows.
+# TYPE gpbackup_backup_sla_violated gauge
+gpbackup_backup_sla_violated{backup_type="full",cluster="",database_name="test"} 0
+# HELP gpbackup_database_backup_missing Whether expected database has no successful backups of required type.
+# TYPE gpbackup_database_backup_missing gauge
+gpbackup_database_backup_missing{backup_type="full",cluster="",database_name="test"} 0
+`
+	// Backup is older than collection depth.
+	backupData := templateBackupConfig()
+	backupData.Timestamp = time.Now().AddDate(0, 0, -10).Format(gpbckpconfig.Layout)
+	backupData.EndTime = backupData.Timestamp
+	historyFile := fakeHistoryFileBackups(t, backupData)
+	defer os.Remove(historyFile)
+	defer delete(historyCaches, historyFile)
+	config := CollectConfig{
+		DBExpected:   []string{"test"},
+		CollectDepth: 7,
+		SLA:          SLAConfig{Default: SLAPolicy{"full": model.Duration(14 * 24 * time.Hour)}},
+	}
+	resetMetrics()
+	if _, err := collectBackupInfo(context.Background(), "", historyFile, config, nil, setUpMetricValue, getLogger()); err != nil {
+		t.Fatalf("\nGet error during collect backup info:\n%v", err)
+	}
+	if got := gatherMetricsText(t, gpbckpBackupSLAViolatedMetric, gpbckpDatabaseBackupMissingMetric); got != templateMetrics {
+		t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", got, templateMetrics)
+	}
+	// Value depends on current time, so only series existence with finite value is checked.
+	got := gatherMetricsText(t, gpbckpBackupSinceLastCompletionSecondsMetric)
+	want := `gpbackup_backup_since_last_completion_seconds{backup_type="full",cluster="",database_name="test"} `
+	if !strings.Contains(got, want) || strings.Contains(got, "+Inf") {
+		t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCollectBackupInfoRPOWithFilters(t *testing.T) {
+	// All backups are older than collection depth
+	// and the newest recovery point is incremental backup.
+	historyFile := fakeHistoryFileBackups(t, templateIncrementalChain()...)
+	defer os.Remove(historyFile)
+	defer delete(historyCaches, historyFile)
+	config := CollectConfig{BackupType: "full", CollectDepth: 7}
+	resetMetrics()
+	if _, err := collectBackupInfo(context.Background(), "", historyFile, config, nil, setUpMetricValue, getLogger()); err != nil {
+		t.Fatalf("\nGet error during collect backup info:\n%v", err)
+	}
+	// Value depends on current time, so only series existence is checked.
+	// Recovery point itself is checked in TestLoadRecoveryPointsDB.
+	got := gatherMetricsText(t, gpbckpDatabaseRPOSecondsMetric)
+	want := `gpbackup_database_rpo_seconds{cluster="",database_name="test"} `
+	if !strings.Contains(got, want) {
+		t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", got, want)
+	}
+}
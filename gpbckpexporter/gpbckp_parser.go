@@ -1,9 +1,9 @@
 package gpbckpexporter
 
 import (
+	"context"
 	"errors"
 	"log/slog"
-	"path/filepath"
 	"strings"
 	"time"
 
@@ -71,6 +71,11 @@ func getDeletedStatusCode(valueDateDeleted string) (string, float64) {
 func resetMetrics() {
 	resetBackupMetrics()
 	resetLastBackupMetrics()
+	resetRestoreChainMetrics()
+	resetSLAMetrics()
+	resetCatalogMetrics()
+	resetInProgressMetrics()
+	resetDeletionMetrics()
 	resetExporterMetrics()
 }
 
@@ -103,29 +108,70 @@ func dbInList(db string, list []string) bool {
 	return false
 }
 
+// Check that db is selected by databases filters.
+// Databases specified in include and exclude lists aren't selected.
+func dbSelected(db string, dbInclude, dbExclude []string) bool {
+	return !dbInList(db, dbExclude) && (listEmpty(dbInclude) || dbInList(db, dbInclude))
+}
+
 // Check list not empty.
 func listEmpty(list []string) bool {
 	return strings.Join(list, "") == ""
 }
 
-// Get and parse data from history database:
-//   - file with extension .db (sqlite).
+// Get and parse data from history file:
+//   - sqlite database (gpbackup_history.db);
+//   - yaml file for legacy gpbackup versions (gpbackup_history.yaml).
 //
-// Returns parsed data or error.
-func parseBackupData(historyFile string, collectDeleted, collectFailed bool, logger *slog.Logger) (gpbckpconfig.History, error) {
-	var parseHData gpbckpconfig.History
-	if filepath.Ext(historyFile) != ".db" {
-		return parseHData, errors.New("file has an extension other than db (sqlite)")
+// Format is detected by file content, for unreadable or empty files - by extension.
+//
+// Returns parsed data, names of databases, which have backups, and statistics
+// of reading history database or error.
+// Backup data is filtered by all filters, database names - only by filters
+// for deleted and failed backups and for databases.
+func parseBackupData(ctx context.Context, historyFile string, filter backupFilter, logger *slog.Logger) (gpbckpconfig.History, []string, historyStats, error) {
+	switch detectHistoryFormat(historyFile) {
+	case historyFormatSQLite:
+		return getDataFromHistoryDBWithRetries(ctx, historyFile, filter, logger)
+	case historyFormatYAML:
+		return getDataFromHistoryYAML(ctx, historyFile, filter, logger)
+	default:
+		return gpbckpconfig.History{}, nil, historyStats{}, &historyError{errWrongExtension, errors.New("file is neither sqlite database nor yaml file")}
 	}
-	return getDataFromHistoryDB(historyFile, collectDeleted, collectFailed, logger)
 }
 
-func getDataFromHistoryDB(historyFile string, collectDeleted, collectFailed bool, logger *slog.Logger) (gpbckpconfig.History, error) {
+func getDataFromHistoryDB(ctx context.Context, historyFile string, filter backupFilter, logger *slog.Logger) (gpbckpconfig.History, []string, historyStats, error) {
+	var (
+		hData gpbckpconfig.History
+		stats historyStats
+	)
+	dbFile := historyFile
+	if historyDBOptions.SnapshotCopy {
+		snapshot, err := copyHistoryFile(historyFile, historyDBOptions.SnapshotDir)
+		if err != nil {
+			logger.Error("Copy gpbackup history db failed", "err", err)
+			return hData, nil, stats, newHistoryError(err)
+		}
+		defer func() {
+			if errRemove := snapshot.remove(); errRemove != nil {
+				logger.Error("Remove copy of gpbackup history db failed", "err", errRemove)
+			}
+		}()
+		dbFile = snapshot.file
+		stats.snapshot = snapshot
+	}
+	// Cache is bound to original history file, not to its copy.
+	return readHistoryDB(ctx, dbFile, historyFile, filter, stats, logger)
+}
+
+// Read data from history database file.
+// Cache for cacheFile is used for backups, empty cacheFile - without cache.
+func readHistoryDB(ctx context.Context, dbFile, cacheFile string, filter backupFilter, stats historyStats, logger *slog.Logger) (gpbckpconfig.History, []string, historyStats, error) {
 	var hData gpbckpconfig.History
-	hDB, err := gpbckpconfig.OpenHistoryDB(historyFile)
+	hDB, err := openHistoryDB(dbFile, historyDBOptions.BusyTimeout)
 	if err != nil {
 		logger.Error("Open gpbackup history db failed", "err", err)
-		return hData, err
+		return hData, nil, stats, newHistoryError(err)
 	}
 	defer func() {
 		errClose := hDB.Close()
@@ -133,19 +179,57 @@ func getDataFromHistoryDB(historyFile string, collectDeleted, collectFailed bool
 			logger.Error("Close gpbackup history db failed", "err", errClose)
 		}
 	}()
-	backupList, err := gpbckpconfig.GetBackupNamesDB(collectDeleted, collectFailed, hDB)
+	// Get data for all selected backups.
+	if cacheFile != "" {
+		hData.BackupConfigs, stats.rowsRead, err = loadBackupConfigsCached(ctx, cacheFile, hDB, filter, logger)
+	} else {
+		hData.BackupConfigs, err = loadBackupConfigsDB(ctx, hDB, filter)
+		stats.rowsRead = len(hData.BackupConfigs)
+	}
 	if err != nil {
 		logger.Error("Get backups from history db failed", "err", err)
-		return hData, err
+		return hData, nil, stats, newHistoryError(err)
 	}
-	// Get data for selected backups.
-	for _, backupName := range backupList {
-		backupData, err := gpbckpconfig.GetBackupDataDB(backupName, hDB)
-		if err != nil {
-			logger.Error("Get backup data from history db failed", "err", err)
-			return hData, err
-		}
-		hData.BackupConfigs = append(hData.BackupConfigs, backupData)
+	dbNames, err := loadDatabaseNamesDB(ctx, hDB, filter)
+	if err != nil {
+		logger.Error("Get databases from history db failed", "err", err)
+		return hData, nil, stats, newHistoryError(err)
+	}
+	stats.dependents, err = loadDependentsDB(ctx, hDB)
+	if err != nil {
+		logger.Error("Get backup dependencies from history db failed", "err", err)
+		return hData, nil, stats, newHistoryError(err)
+	}
+	stats.pendingDeletions, err = loadPendingDeletionsDB(ctx, hDB)
+	if err != nil {
+		logger.Error("Get backups with pending deletion from history db failed", "err", err)
+		return hData, nil, stats, newHistoryError(err)
+	}
+	// Statistics are only informational, so collection doesn't fail without them.
+	filterStats, err := loadFilterStatsDB(ctx, hDB, filter)
+	if err != nil {
+		logger.Warn("Get filter statistics from history db failed", "err", err)
+	}
+	stats.rowsFiltered = filterStats.rowsFiltered
+	// Without states restorability of backups is unknown, so collection doesn't fail.
+	stats.planStates, err = loadRestorePlanStatesDB(ctx, hDB, filter)
+	if err != nil {
+		logger.Warn("Get restore plan backups from history db failed", "err", err)
+	}
+	// Without them the last backups within collection depth are used, so collection doesn't fail.
+	stats.lastBackups, err = loadLastBackupsDB(ctx, hDB, filter)
+	if err != nil {
+		logger.Warn("Get last backups from history db failed", "err", err)
+	}
+	// Without them RPO isn't set, so collection doesn't fail.
+	stats.recoveryPoints, err = loadRecoveryPointsDB(ctx, hDB, filter)
+	if err != nil {
+		logger.Warn("Get recovery points from history db failed", "err", err)
+	}
+	// Without them incremental chain length isn't set, so collection doesn't fail.
+	stats.incrementalChains, err = loadIncrementalChainsDB(ctx, hDB, filter)
+	if err != nil {
+		logger.Warn("Get incremental chains from history db failed", "err", err)
 	}
-	return hData, nil
+	return hData, dbNames, stats, nil
 }
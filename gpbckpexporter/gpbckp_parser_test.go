@@ -2,6 +2,7 @@ package gpbckpexporter
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"log/slog"
 	"os"
@@ -80,7 +81,7 @@ func TestSetUpMetricValue(t *testing.T) {
 		wantErr bool
 	}{
 		{"setUpMetricValueError",
-			args{gpbckpExporterStatusMetric, 0, []string{"demo", "bad"}},
+			args{gpbckpExporterStatusMetric, 0, []string{"cluster", "demo", "bad"}},
 			true,
 		},
 	}
@@ -273,8 +274,8 @@ func TestParseBackupData(t *testing.T) {
 				cDeleted:    false,
 				cFailed:     false,
 			},
-			want:    gpbckpconfig.History{},
-			wantErr: true,
+			want:    gpbckpconfig.History{BackupConfigs: []gpbckpconfig.BackupConfig{}},
+			wantErr: false,
 		},
 		{
 			name: "Test db file",
@@ -304,7 +305,7 @@ func TestParseBackupData(t *testing.T) {
 				t.Fatalf("Failed to create temp file: %v", err)
 			}
 			defer os.Remove(tempFile.Name())
-			got, err := parseBackupData(tempFile.Name(), tt.args.cDeleted, tt.args.cFailed, getLogger())
+			got, _, _, err := parseBackupData(context.Background(), tempFile.Name(), backupFilter{collectDeleted: tt.args.cDeleted, collectFailed: tt.args.cFailed}, getLogger())
 			if (err != nil) != tt.wantErr {
 				t.Errorf("\nVariables do not match:\n%v\nwantErrText:\n%v", err, tt.wantErr)
 			}
@@ -359,7 +360,7 @@ func TestGetDataFromHistoryDB(t *testing.T) {
 				cleanUpTestDB:  true,
 			},
 			true,
-			"level=ERROR msg=\"Get backup data from history db failed\"",
+			"level=ERROR msg=\"Get backups from history db failed\"",
 		},
 	}
 	for _, tt := range tests {
@@ -369,7 +370,7 @@ func TestGetDataFromHistoryDB(t *testing.T) {
 			}
 			out := &bytes.Buffer{}
 			logger := slog.New(slog.NewTextHandler(out, &slog.HandlerOptions{Level: slog.LevelError}))
-			_, err := getDataFromHistoryDB(tt.args.historyFile, tt.args.collectDeleted, tt.args.collectFailed, logger)
+			_, _, _, err := getDataFromHistoryDB(context.Background(), tt.args.historyFile, backupFilter{collectDeleted: tt.args.collectDeleted, collectFailed: tt.args.collectFailed}, logger)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("getDataFromHistoryDB() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -0,0 +1,114 @@
+package gpbckpexporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// ProbeTarget is history source with its own settings for collecting metrics.
+// Metrics for target are collected on demand via probe endpoint.
+type ProbeTarget struct {
+	Name        string
+	HistoryFile string
+	CollectConfig
+}
+
+// Endpoint for collecting metrics for specific target.
+const probeEndpoint = "/probe"
+
+// Probe endpoint is disabled, if there are no targets.
+var probeTargets map[string]ProbeTarget
+
+// ValidateProbeTargets checks that all targets have names and history files,
+// names are unique and settings for collecting metrics are valid.
+func ValidateProbeTargets(targets []ProbeTarget) error {
+	names := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		if target.Name == "" {
+			return fmt.Errorf("empty name for probe target with file %q", target.HistoryFile)
+		}
+		if target.HistoryFile == "" {
+			return fmt.Errorf("empty history file for probe target %q", target.Name)
+		}
+		if names[target.Name] {
+			return fmt.Errorf("duplicate probe target name %q", target.Name)
+		}
+		if err := target.Validate(); err != nil {
+			return fmt.Errorf("probe target %q: %w", target.Name, err)
+		}
+		names[target.Name] = true
+	}
+	return nil
+}
+
+// SetProbeTargets enables HTTP endpoint for collecting metrics for specific target.
+func SetProbeTargets(targets []ProbeTarget) {
+	probeTargets = make(map[string]ProbeTarget, len(targets))
+	for _, target := range targets {
+		probeTargets[target.Name] = target
+	}
+}
+
+// Handler for collecting metrics for target from 'target' query parameter.
+// Metrics are collected into new metric vectors and returned via new registry,
+// so metrics from periodic collection are not changed.
+func probeHandler(targets map[string]ProbeTarget, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("target")
+		if name == "" {
+			http.Error(w, "Target parameter is missing", http.StatusBadRequest)
+			return
+		}
+		target, ok := targets[name]
+		if !ok {
+			http.Error(w, fmt.Sprintf("Unknown target %q", name), http.StatusBadRequest)
+			return
+		}
+		probeSuccessMetric := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "probe_success",
+			Help: "Displays whether or not the probe was a success.",
+		})
+		probeDurationMetric := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "probe_duration_seconds",
+			Help: "Returns how long the probe took to complete in seconds.",
+		})
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(probeSuccessMetric, probeDurationMetric)
+		metricVecs := newMetricVecs()
+		for _, metricVec := range metricVecs {
+			registry.MustRegister(metricVec)
+		}
+		start := time.Now()
+		if err := probeTarget(r.Context(), target, metricVecs, logger.With("target", name)); err == nil {
+			probeSuccessMetric.Set(1)
+		}
+		probeDurationMetric.Set(time.Since(start).Seconds())
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
+}
+
+// Collect metrics for target into new metric vectors.
+// Global metrics and tracker aren't used, so collection isn't serialized with periodic collection.
+// Deletion states of backups aren't tracked, so deletion age isn't set.
+func probeTarget(ctx context.Context, target ProbeTarget, metricVecs map[*prometheus.GaugeVec]*prometheus.GaugeVec, logger *slog.Logger) error {
+	_, err := collectBackupInfo(ctx, target.Name, target.HistoryFile, target.CollectConfig, nil, probeSetUpMetricValueFun(metricVecs), logger)
+	return err
+}
+
+// Set up metric value in new metric vector instead of global one.
+func probeSetUpMetricValueFun(metricVecs map[*prometheus.GaugeVec]*prometheus.GaugeVec) setUpMetricValueFunType {
+	return func(metric *prometheus.GaugeVec, value float64, labels ...string) error {
+		probeMetric, ok := metricVecs[metric]
+		if !ok {
+			return errors.New("metric vector for probe not found")
+		}
+		return setUpMetricValue(probeMetric, value, labels...)
+	}
+}
@@ -0,0 +1,85 @@
+package gpbckpexporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestProbeHandler(t *testing.T) {
+	historyFile := fakeHistoryFileBackups(t, templateBackupConfig())
+	defer os.Remove(historyFile)
+	defer delete(historyCaches, historyFile)
+	targets := map[string]ProbeTarget{
+		"test": {Name: "test", HistoryFile: historyFile},
+	}
+	tests := []struct {
+		name     string
+		url      string
+		wantCode int
+		wantText []string
+	}{
+		{
+			"ValidTarget",
+			"/probe?target=test",
+			http.StatusOK,
+			[]string{
+				"probe_success 1",
+				`gpbackup_backup_status{backup_type="full",cluster="test",database_name="test",object_filtering="none",plugin="none",timestamp="20230118152654"} 0`,
+				`gpbackup_exporter_status{cluster="test",database_name="test"} 1`,
+			},
+		},
+		{
+			"MissingTarget",
+			"/probe",
+			http.StatusBadRequest,
+			[]string{"Target parameter is missing"},
+		},
+		{
+			"UnknownTarget",
+			"/probe?target=unknown",
+			http.StatusBadRequest,
+			[]string{"Unknown target"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetMetrics()
+			rec := httptest.NewRecorder()
+			probeHandler(targets, getLogger()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if rec.Code != tt.wantCode {
+				t.Errorf("\nVariables do not match:\n%d\nwant:\n%d", rec.Code, tt.wantCode)
+			}
+			for _, text := range tt.wantText {
+				if !strings.Contains(rec.Body.String(), text) {
+					t.Errorf("\nResponse doesn't contain:\n%s\nresponse:\n%s", text, rec.Body.String())
+				}
+			}
+			// Global metric vectors must not be changed by probe.
+			reg := prometheus.NewRegistry()
+			for _, metricVec := range collectedMetricVecs() {
+				reg.MustRegister(metricVec)
+			}
+			metricFamily, err := reg.Gather()
+			if err != nil {
+				t.Errorf("\nGet error during gather:\n%v", err)
+			}
+			if len(metricFamily) != 0 {
+				t.Errorf("\nVariables do not match:\n%d\nwant:\n%d", len(metricFamily), 0)
+			}
+		})
+	}
+}
+
+func TestNewMetricVecs(t *testing.T) {
+	metricVecs := newMetricVecs()
+	for _, metricVec := range collectedMetricVecs() {
+		if _, ok := metricVecs[metricVec]; !ok {
+			t.Errorf("\nNo new metric vector for collected metric vector:\n%v", metricVec)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package gpbckpexporter
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+var (
+	gpbckpBackupRestoreChainLengthMetric     = newBackupRestoreChainLengthMetric()
+	gpbckpBackupBaseFullTimestampMetric      = newBackupBaseFullTimestampMetric()
+	gpbckpBackupBaseFullAgeSecondsMetric     = newBackupBaseFullAgeSecondsMetric()
+	gpbckpBackupDependentBackupsMetric       = newBackupDependentBackupsMetric()
+	gpbckpBackupIncrementalChainLengthMetric = newBackupIncrementalChainLengthMetric()
+	gpbckpBackupRestorableMetric             = newBackupRestorableMetric()
+	gpbckpBackupUnrestorableMetric           = newBackupUnrestorableMetric()
+)
+
+// Number of backups, which depend on backup, by backup timestamp.
+type dependentsMap map[string]int
+
+// Number of unrestorable backups by database.
+type unrestorableMap map[string]int
+
+// Restore chain of backup from restore plan.
+type restoreChain struct {
+	// Number of backups required for restore, including backup itself.
+	length int
+	// Timestamp of the base full backup.
+	baseTimestamp string
+}
+
+// Get restore chain of backup.
+// Restore plan contains the base full backup, all incremental backups after it
+// and the backup itself. Backups without restore plan are restored by themselves.
+func getRestoreChain(backupData gpbckpconfig.BackupConfig) restoreChain {
+	chain := restoreChain{length: 1, baseTimestamp: backupData.Timestamp}
+	timestamps := make(map[string]struct{}, len(backupData.RestorePlan))
+	for _, entry := range backupData.RestorePlan {
+		timestamps[entry.Timestamp] = struct{}{}
+		if entry.Timestamp < chain.baseTimestamp {
+			chain.baseTimestamp = entry.Timestamp
+		}
+	}
+	if len(timestamps) > 1 {
+		chain.length = len(timestamps)
+	}
+	return chain
+}
+
+// Get length of incremental chain since the last full backup.
+// For full backup it's 0, for incremental backup - number of incremental backups
+// in its restore chain, including backup itself.
+func getIncrementalChainLength(backupData gpbckpconfig.BackupConfig) int {
+	if !backupData.Incremental {
+		return 0
+	}
+	return max(getRestoreChain(backupData).length-1, 0)
+}
+
+// Set backup restore chain metrics:
+//   - gpbackup_backup_restore_chain_length
+//   - gpbackup_backup_base_full_timestamp_seconds
+//   - gpbackup_backup_base_full_age_seconds
+//   - gpbackup_backup_dependent_backups
+func getBackupChainMetrics(cluster, bckpType string, backupData gpbckpconfig.BackupConfig, dependents dependentsMap, currentUnixTime int64, setUpMetricValueFun setUpMetricValueFunType, logger *slog.Logger) {
+	chain := getRestoreChain(backupData)
+	// Restore chain length.
+	setUpMetric(
+		gpbckpBackupRestoreChainLengthMetric,
+		"gpbackup_backup_restore_chain_length",
+		float64(chain.length),
+		setUpMetricValueFun,
+		logger,
+		bckpType,
+		cluster,
+		backupData.DatabaseName,
+		backupData.Timestamp,
+	)
+	// Number of later backups, which depend on backup.
+	setUpMetric(
+		gpbckpBackupDependentBackupsMetric,
+		"gpbackup_backup_dependent_backups",
+		float64(dependents[backupData.Timestamp]),
+		setUpMetricValueFun,
+		logger,
+		bckpType,
+		cluster,
+		backupData.DatabaseName,
+		backupData.Timestamp,
+	)
+	// The same as for backup timestamp, timezone of Greenplum cluster is taken into account.
+	baseTime, err := time.ParseInLocation(gpbckpconfig.Layout, chain.baseTimestamp, time.Local)
+	if err != nil {
+		logger.Error("Parse base full backup timestamp value failed", "err", err)
+		return
+	}
+	// Base full backup timestamp.
+	setUpMetric(
+		gpbckpBackupBaseFullTimestampMetric,
+		"gpbackup_backup_base_full_timestamp_seconds",
+		float64(baseTime.Unix()),
+		setUpMetricValueFun,
+		logger,
+		bckpType,
+		cluster,
+		backupData.DatabaseName,
+		backupData.Timestamp,
+	)
+	// Seconds since the base full backup.
+	setUpMetric(
+		gpbckpBackupBaseFullAgeSecondsMetric,
+		"gpbackup_backup_base_full_age_seconds",
+		time.Unix(currentUnixTime, 0).Sub(baseTime).Seconds(),
+		setUpMetricValueFun,
+		logger,
+		bckpType,
+		cluster,
+		backupData.DatabaseName,
+		backupData.Timestamp,
+	)
+}
+
+// Check that backup can be restored.
+// Backup is restorable, if all backups from its restore plan, including backup itself,
+// exist in history file, are successful and are not deleted.
+// Backups with failed delete attempts are treated as not deleted, the same as for gpbackman.
+func isBackupRestorable(backupData gpbckpconfig.BackupConfig, planStates map[string]backupState) bool {
+	if !isBackupActiveSuccess(backupData.Status, backupData.DateDeleted) {
+		return false
+	}
+	for _, entry := range backupData.RestorePlan {
+		state, ok := planStates[entry.Timestamp]
+		if !ok || !isBackupActiveSuccess(state.status, state.dateDeleted) {
+			return false
+		}
+	}
+	return true
+}
+
+func isBackupActiveSuccess(status, dateDeleted string) bool {
+	return status == gpbckpconfig.BackupStatusSuccess && gpbckpconfig.IsBackupActive(dateDeleted)
+}
+
+// Set backup restorable metrics:
+//   - gpbackup_backup_restorable
+//
+// Metric is set only for successful and not deleted backups.
+// Without states of backups from restore plans, metric isn't set.
+func getBackupRestorableMetrics(cluster, bckpType string, backupData gpbckpconfig.BackupConfig, planStates map[string]backupState, unrestorable unrestorableMap, setUpMetricValueFun setUpMetricValueFunType, logger *slog.Logger) {
+	if planStates == nil || !isBackupActiveSuccess(backupData.Status, backupData.DateDeleted) {
+		return
+	}
+	restorable := isBackupRestorable(backupData, planStates)
+	if !restorable {
+		unrestorable[backupData.DatabaseName]++
+	} else if _, ok := unrestorable[backupData.DatabaseName]; !ok {
+		unrestorable[backupData.DatabaseName] = 0
+	}
+	setUpMetric(
+		gpbckpBackupRestorableMetric,
+		"gpbackup_backup_restorable",
+		convertBoolToFloat64(restorable),
+		setUpMetricValueFun,
+		logger,
+		bckpType,
+		cluster,
+		backupData.DatabaseName,
+		backupData.Timestamp,
+	)
+}
+
+// Set database unrestorable backups metrics:
+//   - gpbackup_backup_unrestorable_backups
+func getUnrestorableMetrics(cluster string, unrestorable unrestorableMap, setUpMetricValueFun setUpMetricValueFunType, logger *slog.Logger) {
+	for db, count := range unrestorable {
+		setUpMetric(
+			gpbckpBackupUnrestorableMetric,
+			"gpbackup_backup_unrestorable_backups",
+			float64(count),
+			setUpMetricValueFun,
+			logger,
+			cluster,
+			db,
+		)
+	}
+}
+
+// Set database incremental chain metrics:
+//   - gpbackup_backup_incremental_chain_length
+func getIncrementalChainMetrics(cluster string, incrementalChains map[string]int, setUpMetricValueFun setUpMetricValueFunType, logger *slog.Logger) {
+	for db, length := range incrementalChains {
+		setUpMetric(
+			gpbckpBackupIncrementalChainLengthMetric,
+			"gpbackup_backup_incremental_chain_length",
+			float64(length),
+			setUpMetricValueFun,
+			logger,
+			cluster,
+			db,
+		)
+	}
+}
+
+func resetRestoreChainMetrics() {
+	gpbckpBackupRestoreChainLengthMetric.Reset()
+	gpbckpBackupBaseFullTimestampMetric.Reset()
+	gpbckpBackupBaseFullAgeSecondsMetric.Reset()
+	gpbckpBackupDependentBackupsMetric.Reset()
+	gpbckpBackupIncrementalChainLengthMetric.Reset()
+	gpbckpBackupRestorableMetric.Reset()
+	gpbckpBackupUnrestorableMetric.Reset()
+}
+
+func newBackupRestoreChainLengthMetric() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_backup_restore_chain_length",
+		Help: "Number of backups required to restore backup, including backup itself.",
+	},
+		[]string{
+			"backup_type",
+			"cluster",
+			"database_name",
+			"timestamp"})
+}
+
+func newBackupBaseFullTimestampMetric() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_backup_base_full_timestamp_seconds",
+		Help: "Timestamp of the base full backup in restore chain.",
+	},
+		[]string{
+			"backup_type",
+			"cluster",
+			"database_name",
+			"timestamp"})
+}
+
+func newBackupBaseFullAgeSecondsMetric() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_backup_base_full_age_seconds",
+		Help: "Seconds since the base full backup in restore chain.",
+	},
+		[]string{
+			"backup_type",
+			"cluster",
+			"database_name",
+			"timestamp"})
+}
+
+func newBackupDependentBackupsMetric() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_backup_dependent_backups",
+		Help: "Number of later backups, which depend on backup.",
+	},
+		[]string{
+			"backup_type",
+			"cluster",
+			"database_name",
+			"timestamp"})
+}
+
+func newBackupIncrementalChainLengthMetric() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_backup_incremental_chain_length",
+		Help: "Number of incremental backups since the last full backup.",
+	},
+		[]string{
+			"cluster",
+			"database_name"})
+}
+
+func newBackupRestorableMetric() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_backup_restorable",
+		Help: "Whether all backups in restore chain exist and are successful.",
+	},
+		[]string{
+			"backup_type",
+			"cluster",
+			"database_name",
+			"timestamp"})
+}
+
+func newBackupUnrestorableMetric() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_backup_unrestorable_backups",
+		Help: "Number of successful and not deleted backups with broken restore chain.",
+	},
+		[]string{
+			"cluster",
+			"database_name"})
+}
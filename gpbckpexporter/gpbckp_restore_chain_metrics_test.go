@@ -0,0 +1,277 @@
+package gpbckpexporter
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+// Full backup and two incremental backups based on it.
+func templateIncrementalChain() []gpbckpconfig.BackupConfig {
+	full := templateBackupConfig()
+	full.Timestamp = "20230118150000"
+	full.RestorePlan = []gpbckpconfig.RestorePlanEntry{
+		{Timestamp: "20230118150000", TableFQNs: []string{"public.t1"}},
+	}
+	incr1 := templateBackupConfig()
+	incr1.Timestamp = "20230118160000"
+	incr1.Incremental = true
+	incr1.RestorePlan = []gpbckpconfig.RestorePlanEntry{
+		{Timestamp: "20230118150000", TableFQNs: []string{"public.t1"}},
+		{Timestamp: "20230118160000", TableFQNs: []string{"public.t2"}},
+	}
+	incr2 := templateBackupConfig()
+	incr2.Timestamp = "20230118170000"
+	incr2.Incremental = true
+	incr2.RestorePlan = []gpbckpconfig.RestorePlanEntry{
+		{Timestamp: "20230118150000", TableFQNs: []string{"public.t1"}},
+		{Timestamp: "20230118160000", TableFQNs: []string{"public.t2"}},
+		{Timestamp: "20230118170000", TableFQNs: []string{"public.t3"}},
+	}
+	return []gpbckpconfig.BackupConfig{incr2, incr1, full}
+}
+
+func TestGetRestoreChain(t *testing.T) {
+	chain := templateIncrementalChain()
+	tests := []struct {
+		name       string
+		backupData gpbckpconfig.BackupConfig
+		want       restoreChain
+		wantIncr   int
+	}{
+		{"Full", chain[2], restoreChain{1, "20230118150000"}, 0},
+		{"FirstIncremental", chain[1], restoreChain{2, "20230118150000"}, 1},
+		{"SecondIncremental", chain[0], restoreChain{3, "20230118150000"}, 2},
+		{"WithoutRestorePlan", templateBackupConfig(), restoreChain{1, "20230118152654"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getRestoreChain(tt.backupData); got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+			if got := getIncrementalChainLength(tt.backupData); got != tt.wantIncr {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.wantIncr)
+			}
+		})
+	}
+}
+
+func TestCollectBackupInfoDependentsWithFilter(t *testing.T) {
+	historyFile := fakeHistoryFileBackups(t, templateIncrementalChain()...)
+	defer os.Remove(historyFile)
+	defer delete(historyCaches, historyFile)
+	templateMetrics := `# HELP gpbackup_backup_dependent_backups Number of later backups, which depend on backup.
+# TYPE gpbackup_backup_dependent_backups gauge
+gpbackup_backup_dependent_backups{backup_type="full",cluster="",database_name="test",timestamp="20230118150000"} 2
+`
+	resetMetrics()
+	// Incremental backups aren't collected, but they still depend on full backup.
+	if _, err := collectBackupInfo(context.Background(), "", historyFile, CollectConfig{BackupType: "full"}, nil, setUpMetricValue, getLogger()); err != nil {
+		t.Fatalf("\nGet error during collect backup info:\n%v", err)
+	}
+	if got := gatherMetricsText(t, gpbckpBackupDependentBackupsMetric); got != templateMetrics {
+		t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", got, templateMetrics)
+	}
+}
+
+func TestCollectBackupInfoIncrementalChainWithFilters(t *testing.T) {
+	historyFile := fakeHistoryFileBackups(t, templateIncrementalChain()...)
+	defer os.Remove(historyFile)
+	defer delete(historyCaches, historyFile)
+	templateMetrics := `# HELP gpbackup_backup_incremental_chain_length Number of incremental backups since the last full backup.
+# TYPE gpbackup_backup_incremental_chain_length gauge
+gpbackup_backup_incremental_chain_length{cluster="",database_name="test"} 2
+`
+	resetMetrics()
+	// Incremental backups aren't collected and all backups are older than collection depth,
+	// but chain length is still evaluated by the last incremental backup.
+	if _, err := collectBackupInfo(context.Background(), "", historyFile, CollectConfig{BackupType: "full", CollectDepth: 7}, nil, setUpMetricValue, getLogger()); err != nil {
+		t.Fatalf("\nGet error during collect backup info:\n%v", err)
+	}
+	if got := gatherMetricsText(t, gpbckpBackupIncrementalChainLengthMetric); got != templateMetrics {
+		t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", got, templateMetrics)
+	}
+}
+
+func TestGetBackupChainMetrics(t *testing.T) {
+	templateMetrics := `# HELP gpbackup_backup_base_full_age_seconds Seconds since the base full backup in restore chain.
+# TYPE gpbackup_backup_base_full_age_seconds gauge
+gpbackup_backup_base_full_age_seconds{backup_type="incremental",cluster="",database_name="test",timestamp="20230118160000"} 18000
+# HELP gpbackup_backup_base_full_timestamp_seconds Timestamp of the base full backup in restore chain.
+# TYPE gpbackup_backup_base_full_timestamp_seconds gauge
+gpbackup_backup_base_full_timestamp_seconds{backup_type="incremental",cluster="",database_name="test",timestamp="20230118160000"} 1.674054e+09
+# HELP gpbackup_backup_dependent_backups Number of later backups, which depend on backup.
+# TYPE gpbackup_backup_dependent_backups gauge
+gpbackup_backup_dependent_backups{backup_type="incremental",cluster="",database_name="test",timestamp="20230118160000"} 1
+# HELP gpbackup_backup_incremental_chain_length Number of incremental backups since the last full backup.
+# TYPE gpbackup_backup_incremental_chain_length gauge
+gpbackup_backup_incremental_chain_length{cluster="",database_name="test"} 2
+# HELP gpbackup_backup_restore_chain_length Number of backups required to restore backup, including backup itself.
+# TYPE gpbackup_backup_restore_chain_length gauge
+gpbackup_backup_restore_chain_length{backup_type="incremental",cluster="",database_name="test",timestamp="20230118160000"} 2
+`
+	resetRestoreChainMetrics()
+	chain := templateIncrementalChain()
+	getBackupChainMetrics("", "incremental", chain[1], dependentsMap{"20230118150000": 2, "20230118160000": 1}, templateUnixTime(), setUpMetricValue, getLogger())
+	getIncrementalChainMetrics("", map[string]int{"test": getIncrementalChainLength(chain[0])}, setUpMetricValue, getLogger())
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		gpbckpBackupRestoreChainLengthMetric,
+		gpbckpBackupBaseFullTimestampMetric,
+		gpbckpBackupBaseFullAgeSecondsMetric,
+		gpbckpBackupDependentBackupsMetric,
+		gpbckpBackupIncrementalChainLengthMetric,
+	)
+	metricFamily, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("\nGet error during gather metrics:\n%v", err)
+	}
+	out := &bytes.Buffer{}
+	for _, mf := range metricFamily {
+		if _, err := expfmt.MetricFamilyToText(out, mf); err != nil {
+			t.Fatalf("\nGet error during convert metrics:\n%v", err)
+		}
+	}
+	if templateMetrics != out.String() {
+		t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", out.String(), templateMetrics)
+	}
+}
+
+func TestIsBackupRestorable(t *testing.T) {
+	chain := templateIncrementalChain()
+	states := func(modify func(map[string]backupState)) map[string]backupState {
+		planStates := make(map[string]backupState)
+		for _, backupData := range chain {
+			planStates[backupData.Timestamp] = backupState{
+				timestamp:   backupData.Timestamp,
+				status:      backupData.Status,
+				endTime:     backupData.EndTime,
+				dateDeleted: backupData.DateDeleted,
+			}
+		}
+		modify(planStates)
+		return planStates
+	}
+	failedBackup := chain[0]
+	failedBackup.Status = gpbckpconfig.BackupStatusFailure
+	tests := []struct {
+		name       string
+		backupData gpbckpconfig.BackupConfig
+		planStates map[string]backupState
+		want       bool
+	}{
+		{
+			"AllBackupsActive",
+			chain[0],
+			states(func(map[string]backupState) {}),
+			true,
+		},
+		{
+			"FullBackupDeleted",
+			chain[0],
+			states(func(s map[string]backupState) {
+				s["20230118150000"] = backupState{timestamp: "20230118150000", status: "Success", dateDeleted: "20230119150000"}
+			}),
+			false,
+		},
+		{
+			"IncrementalBackupFailed",
+			chain[0],
+			states(func(s map[string]backupState) {
+				s["20230118160000"] = backupState{timestamp: "20230118160000", status: gpbckpconfig.BackupStatusFailure}
+			}),
+			false,
+		},
+		{
+			"IncrementalBackupDeletionInProgress",
+			chain[0],
+			states(func(s map[string]backupState) {
+				s["20230118160000"] = backupState{timestamp: "20230118160000", status: "Success", dateDeleted: gpbckpconfig.DateDeletedInProgress}
+			}),
+			false,
+		},
+		{
+			"FullBackupDeleteFailed",
+			chain[0],
+			states(func(s map[string]backupState) {
+				s["20230118150000"] = backupState{timestamp: "20230118150000", status: "Success", dateDeleted: gpbckpconfig.DateDeletedPluginFailed}
+			}),
+			true,
+		},
+		{
+			"FullBackupMissing",
+			chain[0],
+			states(func(s map[string]backupState) { delete(s, "20230118150000") }),
+			false,
+		},
+		{
+			"BackupItselfFailed",
+			failedBackup,
+			states(func(map[string]backupState) {}),
+			false,
+		},
+		{
+			"WithoutRestorePlan",
+			templateBackupConfig(),
+			map[string]backupState{},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isBackupRestorable(tt.backupData, tt.planStates); got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Deleted backups from restore plans are read, even if deleted backups aren't collected.
+func TestGetBackupRestorableMetrics(t *testing.T) {
+	chain := templateIncrementalChain()
+	chain[2].DateDeleted = "20230119150000"
+	historyFile := fakeHistoryFileBackups(t, chain...)
+	defer os.Remove(historyFile)
+	defer delete(historyCaches, historyFile)
+	hData, _, stats, err := parseBackupData(context.Background(), historyFile, backupFilter{}, getLogger())
+	if err != nil {
+		t.Fatalf("\nUnexpected error:\n%v", err)
+	}
+	resetRestoreChainMetrics()
+	unrestorable := make(unrestorableMap)
+	for _, backupData := range hData.BackupConfigs {
+		getBackupRestorableMetrics("", "incremental", backupData, stats.planStates, unrestorable, setUpMetricValue, getLogger())
+	}
+	getUnrestorableMetrics("", unrestorable, setUpMetricValue, getLogger())
+	templateMetrics := `# HELP gpbackup_backup_restorable Whether all backups in restore chain exist and are successful.
+# TYPE gpbackup_backup_restorable gauge
+gpbackup_backup_restorable{backup_type="incremental",cluster="",database_name="test",timestamp="20230118160000"} 0
+gpbackup_backup_restorable{backup_type="incremental",cluster="",database_name="test",timestamp="20230118170000"} 0
+# HELP gpbackup_backup_unrestorable_backups Number of successful and not deleted backups with broken restore chain.
+# TYPE gpbackup_backup_unrestorable_backups gauge
+gpbackup_backup_unrestorable_backups{cluster="",database_name="test"} 2
+`
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		gpbckpBackupRestorableMetric,
+		gpbckpBackupUnrestorableMetric,
+	)
+	metricFamily, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("\nGet error during gather metrics:\n%v", err)
+	}
+	out := &bytes.Buffer{}
+	for _, mf := range metricFamily {
+		if _, err := expfmt.MetricFamilyToText(out, mf); err != nil {
+			t.Fatalf("\nGet error during convert metrics:\n%v", err)
+		}
+	}
+	if templateMetrics != out.String() {
+		t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", out.String(), templateMetrics)
+	}
+}
@@ -0,0 +1,112 @@
+package gpbckpexporter
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	gpbckpBackupSLADeadlineSecondsMetric = newBackupSLADeadlineSecondsMetric()
+	gpbckpBackupSLAViolatedMetric        = newBackupSLAViolatedMetric()
+)
+
+// Get databases for SLA evaluation:
+// all databases with backups in history file, expected databases and databases with their own policies.
+// Expected databases and databases with policies must be selected by databases filters.
+// Databases, which data isn't collected, are skipped.
+func slaDatabases(sla SLAConfig, dbStatus dbStatusMap, dbExpected, dbInclude, dbExclude []string) []string {
+	dbNames := make([]string, 0, len(dbStatus)+len(dbExpected)+len(sla.Databases))
+	for db, collected := range dbStatus {
+		if collected {
+			dbNames = append(dbNames, db)
+		}
+	}
+	configured := make([]string, 0, len(dbExpected)+len(sla.Databases))
+	configured = append(configured, dbExpected...)
+	for db := range sla.Databases {
+		configured = append(configured, db)
+	}
+	seen := make(map[string]struct{}, len(configured))
+	for _, db := range configured {
+		if _, ok := dbStatus[db]; ok {
+			continue
+		}
+		if _, ok := seen[db]; ok {
+			continue
+		}
+		seen[db] = struct{}{}
+		if db != "" && dbSelected(db, dbInclude, dbExclude) {
+			dbNames = append(dbNames, db)
+		}
+	}
+	return dbNames
+}
+
+// Set SLA metrics:
+//   - gpbackup_backup_sla_deadline_seconds
+//   - gpbackup_backup_sla_violated
+//
+// Deadline is calculated from the end time of the last successful backup of each type.
+// If there are no successful backups of expected type, SLA is violated and deadline isn't set.
+func getBackupSLAMetrics(cluster string, sla SLAConfig, dbNames []string, lastBackups lastBackupMap, currentUnixTime int64, setUpMetricValueFun setUpMetricValueFunType, logger *slog.Logger) {
+	currentTime := time.Unix(currentUnixTime, 0)
+	for _, db := range dbNames {
+		for bckpType, interval := range sla.policy(db) {
+			violated := true
+			if endTime, ok := lastBackups[db][bckpType]; ok {
+				deadline := endTime.Add(time.Duration(interval)).Sub(currentTime)
+				violated = deadline < 0
+				// Seconds until deadline, negative after deadline.
+				setUpMetric(
+					gpbckpBackupSLADeadlineSecondsMetric,
+					"gpbackup_backup_sla_deadline_seconds",
+					deadline.Seconds(),
+					setUpMetricValueFun,
+					logger,
+					bckpType,
+					cluster,
+					db,
+				)
+			}
+			setUpMetric(
+				gpbckpBackupSLAViolatedMetric,
+				"gpbackup_backup_sla_violated",
+				convertBoolToFloat64(violated),
+				setUpMetricValueFun,
+				logger,
+				bckpType,
+				cluster,
+				db,
+			)
+		}
+	}
+}
+
+func resetSLAMetrics() {
+	gpbckpBackupSLADeadlineSecondsMetric.Reset()
+	gpbckpBackupSLAViolatedMetric.Reset()
+}
+
+func newBackupSLADeadlineSecondsMetric() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_backup_sla_deadline_seconds",
+		Help: "Seconds until the next successful backup is expected by SLA, negative after deadline.",
+	},
+		[]string{
+			"backup_type",
+			"cluster",
+			"database_name"})
+}
+
+func newBackupSLAViolatedMetric() *prometheus.GaugeVec {
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gpbackup_backup_sla_violated",
+		Help: "Whether the last successful backup is older than SLA allows.",
+	},
+		[]string{
+			"backup_type",
+			"cluster",
+			"database_name"})
+}
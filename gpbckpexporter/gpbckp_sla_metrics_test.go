@@ -0,0 +1,91 @@
+package gpbckpexporter
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+)
+
+func TestSLADatabases(t *testing.T) {
+	sla := SLAConfig{
+		Databases: map[string]SLAPolicy{
+			"test":  {"full": model.Duration(time.Hour)},
+			"test2": {"full": model.Duration(time.Hour)},
+			"test3": {"full": model.Duration(time.Hour)},
+		},
+	}
+	dbStatus := dbStatusMap{"test": true, "test4": false}
+	tests := []struct {
+		name       string
+		dbExpected []string
+		dbInclude  []string
+		dbExclude  []string
+		want       []string
+	}{
+		{"WithoutFilters", []string{""}, []string{""}, []string{""}, []string{"test", "test2", "test3"}},
+		{"IncludeFilter", []string{""}, []string{"test2"}, []string{""}, []string{"test", "test2"}},
+		{"ExcludeFilter", []string{""}, []string{""}, []string{"test3"}, []string{"test", "test2"}},
+		{"ExpectedDatabases", []string{"test2", "test5"}, []string{""}, []string{""}, []string{"test", "test2", "test3", "test5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := slaDatabases(sla, dbStatus, tt.dbExpected, tt.dbInclude, tt.dbExclude)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetBackupSLAMetrics(t *testing.T) {
+	sla := SLAConfig{
+		Default: SLAPolicy{"full": model.Duration(7 * time.Hour)},
+		Databases: map[string]SLAPolicy{
+			"test": {"incremental": model.Duration(time.Hour)},
+		},
+	}
+	lastBackups := lastBackupMap{
+		"test": backupMap{
+			"full":        returnTimeTime("20230118150000"),
+			"incremental": returnTimeTime("20230118160000"),
+		},
+	}
+	templateMetrics := `# HELP gpbackup_backup_sla_deadline_seconds Seconds until the next successful backup is expected by SLA, negative after deadline.
+# TYPE gpbackup_backup_sla_deadline_seconds gauge
+gpbackup_backup_sla_deadline_seconds{backup_type="full",cluster="",database_name="test"} 7200
+gpbackup_backup_sla_deadline_seconds{backup_type="incremental",cluster="",database_name="test"} -10800
+# HELP gpbackup_backup_sla_violated Whether the last successful backup is older than SLA allows.
+# TYPE gpbackup_backup_sla_violated gauge
+gpbackup_backup_sla_violated{backup_type="full",cluster="",database_name="test"} 0
+gpbackup_backup_sla_violated{backup_type="full",cluster="",database_name="test2"} 1
+gpbackup_backup_sla_violated{backup_type="incremental",cluster="",database_name="test"} 1
+`
+	resetSLAMetrics()
+	getBackupSLAMetrics("", sla, []string{"test", "test2"}, lastBackups, templateUnixTime(), setUpMetricValue, getLogger())
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		gpbckpBackupSLADeadlineSecondsMetric,
+		gpbckpBackupSLAViolatedMetric,
+	)
+	metricFamily, err := reg.Gather()
+	if err != nil {
+		fmt.Println(err)
+	}
+	out := &bytes.Buffer{}
+	for _, mf := range metricFamily {
+		if _, err := expfmt.MetricFamilyToText(out, mf); err != nil {
+			panic(err)
+		}
+	}
+	if templateMetrics != out.String() {
+		t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", out.String(), templateMetrics)
+	}
+}
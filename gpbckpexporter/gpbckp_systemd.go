@@ -0,0 +1,64 @@
+package gpbckpexporter
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/daemon"
+)
+
+// Send notification to systemd.
+// When exporter isn't run by systemd with Type=notify, it does nothing.
+var sdNotify = daemon.SdNotify
+
+// NotifyReady notifies systemd that exporter is started,
+// after HTTP listener is up and the first collection for all history sources is finished.
+func NotifyReady(ctx context.Context, logger *slog.Logger) {
+	select {
+	case <-webListening:
+	case <-ctx.Done():
+		return
+	}
+	select {
+	case <-gpbckpHealth.firstCollection():
+	case <-ctx.Done():
+		return
+	}
+	sendNotify(daemon.SdNotifyReady, logger)
+}
+
+// NotifyStopping notifies systemd that exporter is stopping.
+func NotifyStopping(logger *slog.Logger) {
+	sendNotify(daemon.SdNotifyStopping, logger)
+}
+
+// SystemdWatchdogInterval returns watchdog timeout set by systemd via WatchdogSec.
+// 0 - watchdog is disabled.
+func SystemdWatchdogInterval() (time.Duration, error) {
+	return daemon.SdWatchdogEnabled(false)
+}
+
+// Notify systemd about collection result.
+// Watchdog is updated, when collections for all history sources are finished, even with errors.
+// So exporter is restarted by systemd only when collection loop is stuck,
+// errors for history sources are reported via readiness endpoint and metrics.
+func notifyCollection(cluster string, series int, duration time.Duration, err error, cycleDone bool, logger *slog.Logger) {
+	status := fmt.Sprintf("STATUS=Last collection: cluster=%q, series=%d, duration=%s", cluster, series, duration.Round(time.Millisecond))
+	if err != nil {
+		// Notification consists of lines, so error must be one line.
+		status = fmt.Sprintf("%s, error: %s", status, strings.ReplaceAll(err.Error(), "\n", " "))
+	}
+	if cycleDone {
+		status += "\n" + daemon.SdNotifyWatchdog
+	}
+	sendNotify(status, logger)
+}
+
+func sendNotify(state string, logger *slog.Logger) {
+	if _, err := sdNotify(false, state); err != nil {
+		logger.Warn("Notify systemd failed", "err", err)
+	}
+}
@@ -0,0 +1,105 @@
+package gpbckpexporter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Replace notification to systemd and capture sent states.
+func captureSdNotify(t *testing.T) *[]string {
+	t.Helper()
+	var states []string
+	saved := sdNotify
+	sdNotify = func(_ bool, state string) (bool, error) {
+		states = append(states, state)
+		return true, nil
+	}
+	t.Cleanup(func() { sdNotify = saved })
+	return &states
+}
+
+func TestNotifyCollection(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		cycleDone bool
+		wantState string
+	}{
+		{
+			"SuccessfulCollection",
+			nil,
+			true,
+			"STATUS=Last collection: cluster=\"test\", series=10, duration=1.5s\nWATCHDOG=1",
+		},
+		{
+			"SuccessfulCollectionNotAllSources",
+			nil,
+			false,
+			"STATUS=Last collection: cluster=\"test\", series=10, duration=1.5s",
+		},
+		{
+			"FailedCollection",
+			errors.New("database is locked"),
+			false,
+			"STATUS=Last collection: cluster=\"test\", series=10, duration=1.5s, error: database is locked",
+		},
+		{
+			"MultilineError",
+			errors.New("open failed\ndatabase is locked"),
+			false,
+			"STATUS=Last collection: cluster=\"test\", series=10, duration=1.5s, error: open failed database is locked",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			states := captureSdNotify(t)
+			notifyCollection("test", 10, 1500*time.Millisecond, tt.err, tt.cycleDone, getLogger())
+			if len(*states) != 1 || (*states)[0] != tt.wantState {
+				t.Errorf("\nVariables do not match:\n%q\nwant:\n%q", *states, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestNotifyReady(t *testing.T) {
+	states := captureSdNotify(t)
+	savedHealth, savedListening := gpbckpHealth, webListening
+	t.Cleanup(func() { gpbckpHealth, webListening = savedHealth, savedListening })
+	gpbckpHealth = newCollectionHealth([]string{""}, 0)
+	webListening = make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		NotifyReady(context.Background(), getLogger())
+		close(done)
+	}()
+	close(webListening)
+	select {
+	case <-done:
+		t.Fatal("Ready is sent before the first collection")
+	case <-time.After(100 * time.Millisecond):
+	}
+	gpbckpHealth.record("", errors.New("no such file or directory"))
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Ready isn't sent after the first collection")
+	}
+	if len(*states) != 1 || (*states)[0] != "READY=1" {
+		t.Errorf("\nVariables do not match:\n%q\nwant:\n%q", *states, "READY=1")
+	}
+}
+
+func TestNotifyReadyCanceled(t *testing.T) {
+	states := captureSdNotify(t)
+	savedListening := webListening
+	t.Cleanup(func() { webListening = savedListening })
+	webListening = make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	NotifyReady(ctx, getLogger())
+	if len(*states) != 0 {
+		t.Errorf("\nUnexpected notifications:\n%q", *states)
+	}
+}
@@ -0,0 +1,81 @@
+package gpbckpexporter
+
+import (
+	"log/slog"
+	"path/filepath"
+	"time"
+)
+
+// Suffixes of files, which SQLite creates near the database file.
+// Changes in these files also mean changes in history database.
+var historyFileSuffixes = []string{"", "-wal", "-journal"}
+
+// Name of event, when events queue overflowed and some events are lost.
+// Slash can't be used in file names, so it doesn't match any real file.
+const overflowEvent = "/"
+
+// Maximum wait after the first change in terms of debounce intervals.
+// History file can be changed continuously (for example, during long backup of several databases),
+// metrics are collected at least once per such interval anyway.
+const debounceMaxWaitFactor = 12
+
+// WatchHistoryFile watches history file and its -wal/-journal files for changes.
+// The returned channel receives a value when there were changes
+// and no new changes during debounce interval, but not later than
+// debounceMaxWaitFactor debounce intervals after the first change.
+// Watching is stopped, when stop channel is closed.
+func WatchHistoryFile(historyFile string, debounce time.Duration, stop <-chan struct{}, logger *slog.Logger) (<-chan struct{}, error) {
+	watchedNames := make(map[string]bool, len(historyFileSuffixes))
+	for _, suffix := range historyFileSuffixes {
+		watchedNames[filepath.Base(historyFile)+suffix] = true
+	}
+	// The directory is watched, not the file itself.
+	// So file replacement and creating of -wal/-journal files are also detected.
+	events, err := watchDir(filepath.Dir(historyFile), stop, logger)
+	if err != nil {
+		return nil, err
+	}
+	trigger := make(chan struct{}, 1)
+	go debounceEvents(events, watchedNames, debounce, debounceMaxWaitFactor*debounce, trigger, logger)
+	return trigger, nil
+}
+
+// Send value to trigger channel after the last event for watched files,
+// if there are no new events during debounce interval
+// or maxWait interval passed since the first event.
+// If trigger channel already contains value, new value is not sent.
+func debounceEvents(events <-chan string, watchedNames map[string]bool, debounce, maxWait time.Duration, trigger chan<- struct{}, logger *slog.Logger) {
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	// Time, when trigger is sent regardless of new events.
+	// Zero value - there are no events since the last trigger.
+	var deadline time.Time
+	for {
+		select {
+		case name, ok := <-events:
+			if !ok {
+				timer.Stop()
+				return
+			}
+			switch {
+			case name == overflowEvent:
+				// Lost events may contain changes of history file, so metrics are collected anyway.
+				logger.Debug("Events are lost, history file may be changed")
+			case watchedNames[name]:
+				logger.Debug("History file change detected", "file", name)
+			default:
+				continue
+			}
+			if deadline.IsZero() {
+				deadline = time.Now().Add(maxWait)
+			}
+			timer.Reset(min(debounce, time.Until(deadline)))
+		case <-timer.C:
+			deadline = time.Time{}
+			select {
+			case trigger <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+//go:build linux
+
+package gpbckpexporter
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Only events about data changes and file replacement are processed.
+// Events about creating, deleting, opening and closing files are ignored,
+// because SQLite can create and delete -wal file when exporter only reads history database.
+const watchMask = unix.IN_MODIFY | unix.IN_MOVED_TO
+
+// Watch directory via inotify.
+// The returned channel receives names of changed files in directory.
+func watchDir(dir string, stop <-chan struct{}, logger *slog.Logger) (<-chan string, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := unix.InotifyAddWatch(fd, dir, watchMask); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	// Non-blocking descriptor is used via runtime poller,
+	// so blocked read is interrupted when file is closed.
+	inotifyFile := os.NewFile(uintptr(fd), "inotify")
+	events := make(chan string)
+	go func() {
+		<-stop
+		inotifyFile.Close()
+	}()
+	go func() {
+		defer close(events)
+		buf := make([]byte, 4096*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+		for {
+			n, err := inotifyFile.Read(buf)
+			if err != nil {
+				if !errors.Is(err, os.ErrClosed) {
+					logger.Error("Read inotify events failed", "err", err)
+				}
+				return
+			}
+			for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+				event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+				nameStart := offset + unix.SizeofInotifyEvent
+				nameEnd := nameStart + int(event.Len)
+				if nameEnd > n {
+					break
+				}
+				offset = nameEnd
+				name := string(bytes.TrimRight(buf[nameStart:nameEnd], "\x00"))
+				if event.Mask&unix.IN_Q_OVERFLOW != 0 {
+					logger.Warn("Inotify events queue overflowed")
+					name = overflowEvent
+				}
+				select {
+				case events <- name:
+				case <-stop:
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
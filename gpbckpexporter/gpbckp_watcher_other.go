@@ -0,0 +1,13 @@
+//go:build !linux
+
+package gpbckpexporter
+
+import (
+	"errors"
+	"log/slog"
+)
+
+// Watch mode is based on inotify and is supported only on Linux.
+func watchDir(dir string, stop <-chan struct{}, logger *slog.Logger) (<-chan string, error) {
+	return nil, errors.New("watching history file is supported only on Linux")
+}
@@ -0,0 +1,99 @@
+package gpbckpexporter
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestDebounceEvents(t *testing.T) {
+	tests := []struct {
+		name        string
+		events      []string
+		wantTrigger bool
+	}{
+		{"WatchedFiles", []string{"gpbackup_history.db-journal", "gpbackup_history.db"}, true},
+		{"OtherFiles", []string{"gpbackup_history.db-shm", "other.db"}, false},
+		{"Overflow", []string{overflowEvent}, true},
+	}
+	watchedNames := map[string]bool{
+		"gpbackup_history.db":         true,
+		"gpbackup_history.db-wal":     true,
+		"gpbackup_history.db-journal": true,
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := make(chan string)
+			trigger := make(chan struct{}, 1)
+			go debounceEvents(events, watchedNames, 50*time.Millisecond, time.Second, trigger, getLogger())
+			for _, event := range tt.events {
+				events <- event
+			}
+			var gotTrigger bool
+			select {
+			case <-trigger:
+				gotTrigger = true
+			case <-time.After(300 * time.Millisecond):
+			}
+			close(events)
+			if gotTrigger != tt.wantTrigger {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", gotTrigger, tt.wantTrigger)
+			}
+		})
+	}
+}
+
+func TestDebounceEventsMaxWait(t *testing.T) {
+	events := make(chan string)
+	trigger := make(chan struct{}, 1)
+	go debounceEvents(events, map[string]bool{"gpbackup_history.db": true}, 50*time.Millisecond, 150*time.Millisecond, trigger, getLogger())
+	defer close(events)
+	// Events are sent more often than debounce interval, trigger is sent after max wait interval.
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case <-ticker.C:
+			events <- "gpbackup_history.db"
+		case <-trigger:
+			return
+		case <-timeout:
+			t.Fatalf("\nNo trigger during continuous history file changes")
+		}
+	}
+}
+
+func TestWatchHistoryFile(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("watching history file is supported only on Linux")
+	}
+	historyFile := filepath.Join(t.TempDir(), "gpbackup_history.db")
+	if err := os.WriteFile(historyFile, []byte("init"), 0600); err != nil {
+		t.Fatalf("Failed to create history file: %v", err)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	trigger, err := WatchHistoryFile(historyFile, 50*time.Millisecond, stop, getLogger())
+	if err != nil {
+		t.Fatalf("\nGet error during watch history file:\n%v", err)
+	}
+	// Burst of writes must lead to one trigger.
+	for i := 0; i < 3; i++ {
+		if err := os.WriteFile(historyFile+"-journal", []byte("data"), 0600); err != nil {
+			t.Fatalf("Failed to write journal file: %v", err)
+		}
+	}
+	select {
+	case <-trigger:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("\nNo trigger after history file change")
+	}
+	select {
+	case <-trigger:
+		t.Errorf("\nUnexpected second trigger after history file change")
+	case <-time.After(200 * time.Millisecond):
+	}
+}